@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomtwinkle/go-pr-release/internal/release"
+)
+
+type actionInput struct {
+	name string
+	// env is the environment variable the CLI reads the input from. Inputs
+	// without one are passed as the flag of the same name.
+	env string
+	// separator replaces newlines so lists can be written as YAML block
+	// scalars in workflows.
+	separator string
+}
+
+// actionInputs lists the inputs of action.yml besides command and
+// working-directory. Keep action.yml in sync with it.
+var actionInputs = []actionInput{
+	{name: "token", env: "GIT_PR_RELEASE_TOKEN"},
+	{name: "app-id", env: "GIT_PR_RELEASE_APP_ID"},
+	{name: "app-private-key", env: "GIT_PR_RELEASE_APP_PRIVATE_KEY"},
+	{name: "app-installation-id", env: "GIT_PR_RELEASE_APP_INSTALLATION_ID"},
+	{name: "github-api", env: "GIT_PR_RELEASE_GITHUB_API"},
+	{name: "git-backend", env: "GIT_PR_RELEASE_GIT_BACKEND"},
+	{name: "profile", env: "GIT_PR_RELEASE_PROFILE"},
+	{name: "title", env: "GIT_PR_RELEASE_TITLE"},
+	{name: "production-branch", env: "GIT_PR_RELEASE_BRANCH_PRODUCTION"},
+	{name: "staging-branch", env: "GIT_PR_RELEASE_BRANCH_STAGING"},
+	{name: "template", env: "GIT_PR_RELEASE_TEMPLATE"},
+	{name: "stages", env: "GIT_PR_RELEASE_STAGES", separator: ","},
+	{name: "labels", env: "GIT_PR_RELEASE_LABELS", separator: ","},
+	{name: "reviewers", env: "GIT_PR_RELEASE_REVIEWERS", separator: ","},
+	{name: "categories", env: "GIT_PR_RELEASE_CATEGORIES", separator: ";"},
+	{name: "exclude-labels", env: "GIT_PR_RELEASE_EXCLUDE_LABELS", separator: ","},
+	{name: "include-paths", env: "GIT_PR_RELEASE_INCLUDE_PATHS", separator: ","},
+	{name: "exclude-paths", env: "GIT_PR_RELEASE_EXCLUDE_PATHS", separator: ","},
+	{name: "mention", env: "GIT_PR_RELEASE_MENTION"},
+	{name: "assign-pr-author", env: "GIT_PR_RELEASE_ASSIGN_PR_AUTHOR"},
+	{name: "request-pr-author-review", env: "GIT_PR_RELEASE_REQUEST_PR_AUTHOR_REVIEW"},
+	{name: "dry-run", env: "GIT_PR_RELEASE_DRY_RUN"},
+	{name: "json"},
+	{name: "no-fetch"},
+	{name: "squashed"},
+	{name: "rebased", env: "GIT_PR_RELEASE_REBASED"},
+	{name: "squash-detection", env: "GIT_PR_RELEASE_SQUASH_DETECTION"},
+	{name: "pr-lookup", env: "GIT_PR_RELEASE_PR_LOOKUP"},
+	{name: "overwrite-description"},
+	{name: "publish", env: "GIT_PR_RELEASE_PUBLISH"},
+	{name: "release-template", env: "GIT_PR_RELEASE_RELEASE_TEMPLATE"},
+	{name: "tag-pattern", env: "GIT_PR_RELEASE_TAG_PATTERN"},
+	{name: "draft", env: "GIT_PR_RELEASE_DRAFT"},
+	{name: "prerelease", env: "GIT_PR_RELEASE_PRERELEASE"},
+	{name: "changelog", env: "GIT_PR_RELEASE_CHANGELOG"},
+	{name: "changelog-path", env: "GIT_PR_RELEASE_CHANGELOG_PATH"},
+	{name: "changelog-template", env: "GIT_PR_RELEASE_CHANGELOG_TEMPLATE"},
+	{name: "changelog-commit", env: "GIT_PR_RELEASE_CHANGELOG_COMMIT"},
+	{name: "check", env: "GIT_PR_RELEASE_CHECK"},
+	{name: "finalize", env: "GIT_PR_RELEASE_FINALIZE"},
+	{name: "merge-method", env: "GIT_PR_RELEASE_MERGE_METHOD"},
+	{name: "auto-merge", env: "GIT_PR_RELEASE_AUTO_MERGE"},
+	{name: "cache-dir", env: "GIT_PR_RELEASE_CACHE_DIR"},
+	{name: "cache-max-size", env: "GIT_PR_RELEASE_CACHE_MAX_SIZE"},
+	{name: "no-cache", env: "GIT_PR_RELEASE_NO_CACHE"},
+	{name: "max-retries", env: "GIT_PR_RELEASE_MAX_RETRIES"},
+	{name: "retry-base-delay", env: "GIT_PR_RELEASE_RETRY_BASE_DELAY"},
+	{name: "retry-max-delay", env: "GIT_PR_RELEASE_RETRY_MAX_DELAY"},
+	{name: "ssl-no-verify", env: "GIT_PR_RELEASE_SSL_NO_VERIFY"},
+	{name: "color", env: "GIT_PR_RELEASE_COLOR"},
+	{name: "verbose"},
+}
+
+// RunAction is the entrypoint of the GitHub Action in action.yml.
+func RunAction(name, version, commit, date string) int {
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return ExecuteAction(context.Background(), CommandOptions{
+		Name:      name,
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		WorkDir:   workDir,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+		LookupEnv: os.LookupEnv,
+		NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+			return release.NewService(config, stdout, stderr)
+		},
+	})
+}
+
+// ExecuteAction runs the CLI with the INPUT_* variables the runner sets for
+// action inputs. Inputs are served through LookupEnv as the environment
+// variables the CLI already reads, so an input behaves exactly like setting
+// that variable; empty inputs fall through to the environment, the
+// configuration file and git config. GITHUB_TOKEN is only used when none of
+// them sets a token.
+func ExecuteAction(ctx context.Context, options CommandOptions) int {
+	if options.LookupEnv == nil {
+		options.LookupEnv = os.LookupEnv
+	}
+	lookupInput := actionInputLookup(options.LookupEnv)
+	if token, ok := options.LookupEnv("GITHUB_TOKEN"); ok {
+		options.fallbackToken = strings.TrimSpace(token)
+	}
+
+	var args []string
+	if command, ok := lookupInput("command"); ok {
+		args = append(args, command)
+	}
+	inputsByEnv := make(map[string]actionInput, len(actionInputs))
+	for _, input := range actionInputs {
+		if input.env != "" {
+			inputsByEnv[input.env] = input
+			continue
+		}
+		if value, ok := lookupInput(input.name); ok {
+			args = append(args, "--"+input.name+"="+value)
+		}
+	}
+	options.Args = args
+
+	if dir, ok := lookupInput("working-directory"); ok {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(options.WorkDir, dir)
+		}
+		options.WorkDir = dir
+	}
+
+	lookupEnv := options.LookupEnv
+	options.LookupEnv = func(key string) (string, bool) {
+		if input, ok := inputsByEnv[key]; ok {
+			if value, ok := lookupInput(input.name); ok {
+				if input.separator != "" {
+					value = joinLines(value, input.separator)
+				}
+				return value, true
+			}
+		}
+		return lookupEnv(key)
+	}
+
+	return ExecuteContext(ctx, options)
+}
+
+// actionInputLookup reads an input the way @actions/core does: the name is
+// upper-cased with spaces replaced, and blank values count as unset.
+func actionInputLookup(lookupEnv func(string) (string, bool)) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, _ := lookupEnv("INPUT_" + strings.ToUpper(strings.ReplaceAll(name, " ", "_")))
+		value = strings.TrimSpace(value)
+		return value, value != ""
+	}
+}
+
+func joinLines(value, separator string) string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, separator)
+}
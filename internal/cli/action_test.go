@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/tomtwinkle/go-pr-release/internal/release"
+	"gopkg.in/yaml.v3"
+)
+
+func TestExecuteActionReadsInputs(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	var got release.Config
+	var stderr bytes.Buffer
+	exitCode := ExecuteAction(context.Background(), CommandOptions{
+		WorkDir: filepath.Dir(workDir),
+		Stderr:  &stderr,
+		LookupEnv: lookupFromMap(map[string]string{
+			"INPUT_COMMAND":           "preview",
+			"INPUT_WORKING-DIRECTORY": filepath.Base(workDir),
+			"INPUT_TOKEN":             "token",
+			"INPUT_PRODUCTION-BRANCH": "main",
+			"INPUT_LABELS":            "release\n  qa\n",
+			"INPUT_CATEGORIES":        "Features:feature\nBug fixes:bug",
+			"INPUT_SQUASHED":          "true",
+			"INPUT_STAGING-BRANCH":    "",
+			"INPUT_DRY-RUN":           " ",
+			"GIT_PR_RELEASE_DRY_RUN":  "true",
+			"GITHUB_ACTIONS":          "true",
+		}),
+		NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+			got = config
+			return stubService{}
+		},
+	})
+	if exitCode != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	if got.Command != release.CommandPreview || got.WorkDir != workDir {
+		t.Fatalf("unexpected command %q in %q", got.Command, got.WorkDir)
+	}
+	if got.Token != "token" || got.ProductionBranch != "main" || got.StagingBranch != "staging" {
+		t.Fatalf("unexpected config: token=%q production=%q staging=%q", got.Token, got.ProductionBranch, got.StagingBranch)
+	}
+	if !reflect.DeepEqual(got.Labels, []string{"release", "qa"}) {
+		t.Fatalf("expected newline separated labels, got %v", got.Labels)
+	}
+	if len(got.Categories) != 2 || got.Categories[1].Title != "Bug fixes" {
+		t.Fatalf("expected newline separated categories, got %+v", got.Categories)
+	}
+	if !got.Squashed {
+		t.Fatalf("expected the squashed input to be passed as a flag")
+	}
+	if !got.DryRun {
+		t.Fatalf("expected a blank input to fall back to the environment")
+	}
+	if !got.Actions.Enabled {
+		t.Fatalf("expected the GitHub Actions environment to be detected")
+	}
+}
+
+func TestExecuteActionTokenPrecedence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{name: "input", env: map[string]string{"INPUT_TOKEN": "input", "GIT_PR_RELEASE_TOKEN": "env", "GITHUB_TOKEN": "actions"}, want: "input"},
+		{name: "environment", env: map[string]string{"GIT_PR_RELEASE_TOKEN": "env", "GITHUB_TOKEN": "actions"}, want: "env"},
+		{name: "legacy environment", env: map[string]string{"GO_PR_RELEASE_TOKEN": "legacy", "GITHUB_TOKEN": "actions"}, want: "legacy"},
+		{name: "workflow token", env: map[string]string{"GITHUB_TOKEN": "actions"}, want: "actions"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workDir := initGitRepository(t, "git@github.com:octo/example.git")
+			var got release.Config
+			var stderr bytes.Buffer
+			exitCode := ExecuteAction(context.Background(), CommandOptions{
+				WorkDir:   workDir,
+				Stderr:    &stderr,
+				LookupEnv: lookupFromMap(tt.env),
+				NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+					got = config
+					return stubService{}
+				},
+			})
+			if exitCode != 0 {
+				t.Fatalf("expected exit code 0, got %d: %s", exitCode, stderr.String())
+			}
+			if got.Token != tt.want {
+				t.Fatalf("got token %q, want %q", got.Token, tt.want)
+			}
+		})
+	}
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	runGit(t, workDir, "config", "pr-release.token", "git-config")
+	var got release.Config
+	ExecuteAction(context.Background(), CommandOptions{
+		WorkDir:   workDir,
+		LookupEnv: lookupFromMap(map[string]string{"GITHUB_TOKEN": "actions"}),
+		NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+			got = config
+			return stubService{}
+		},
+	})
+	if got.Token != "git-config" {
+		t.Fatalf("expected git config to win over GITHUB_TOKEN, got %q", got.Token)
+	}
+}
+
+func TestActionYAMLMatchesInputs(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "action.yml"))
+	if err != nil {
+		t.Fatalf("read action.yml: %v", err)
+	}
+	var action struct {
+		Inputs  map[string]any `yaml:"inputs"`
+		Outputs map[string]any `yaml:"outputs"`
+	}
+	if err := yaml.Unmarshal(data, &action); err != nil {
+		t.Fatalf("decode action.yml: %v", err)
+	}
+
+	want := []string{"command", "working-directory"}
+	for _, input := range actionInputs {
+		want = append(want, input.name)
+	}
+	var inputs []string
+	for name := range action.Inputs {
+		inputs = append(inputs, name)
+	}
+	slices.Sort(want)
+	slices.Sort(inputs)
+	if !reflect.DeepEqual(inputs, want) {
+		t.Fatalf("action.yml inputs %v do not match %v", inputs, want)
+	}
+
+	var outputs []string
+	for name := range action.Outputs {
+		outputs = append(outputs, name)
+	}
+	slices.Sort(outputs)
+	wantOutputs := []string{"merged-pr-numbers", "mode", "pr-number", "pr-url", "release-url", "stages", "suggested-version", "tag"}
+	if !reflect.DeepEqual(outputs, wantOutputs) {
+		t.Fatalf("action.yml outputs %v do not match %v", outputs, wantOutputs)
+	}
+}
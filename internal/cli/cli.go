@@ -9,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/tomtwinkle/go-pr-release/internal/release"
 )
@@ -28,6 +29,9 @@ type CommandOptions struct {
 	Stderr     io.Writer
 	LookupEnv  func(string) (string, bool)
 	NewService func(release.Config, io.Writer, io.Writer) serviceRunner
+
+	// fallbackToken is used when no token is configured anywhere else.
+	fallbackToken string
 }
 
 func Run(name, version, commit, date string) int {
@@ -86,7 +90,8 @@ func ExecuteContext(ctx context.Context, options CommandOptions) int {
 		return 0
 	}
 
-	config, err := resolveConfig(ctx, options.WorkDir, options.LookupEnv, parsed)
+	resolver := &configResolver{lookupEnv: options.LookupEnv, fallbackToken: options.fallbackToken}
+	config, err := resolver.resolve(ctx, options.WorkDir, parsed)
 	if err != nil {
 		fmt.Fprintln(options.Stderr, err)
 		return 1
@@ -94,6 +99,15 @@ func ExecuteContext(ctx context.Context, options CommandOptions) int {
 
 	if config.Verbose {
 		fmt.Fprintf(options.Stderr, "repository=%s production=%s staging=%s template=%s\n", config.Repository.FullName(), config.ProductionBranch, config.StagingBranch, config.TemplatePath)
+		for _, stage := range config.Stages {
+			fmt.Fprintf(options.Stderr, "stage=%s staging=%s production=%s template=%s\n", stage.Name, stage.StagingBranch, stage.ProductionBranch, stage.TemplatePath)
+		}
+		if len(resolver.profiles) > 0 {
+			fmt.Fprintf(options.Stderr, "profiles=%s\n", strings.Join(resolver.profiles, " -> "))
+		}
+		for _, source := range resolver.sources {
+			fmt.Fprintf(options.Stderr, "%s=%s (%s)\n", source.name, source.value, source.source)
+		}
 	}
 
 	service := options.NewService(config, options.Stdout, options.Stderr)
@@ -101,7 +115,13 @@ func ExecuteContext(ctx context.Context, options CommandOptions) int {
 		if errors.Is(err, release.ErrNoPullRequestsToRelease) {
 			return 1
 		}
+		if errors.Is(err, release.ErrReleaseNotReady) {
+			return 4
+		}
 		fmt.Fprintln(options.Stderr, err)
+		if errors.Is(err, release.ErrPartialFailure) {
+			return 3
+		}
 		return 1
 	}
 
@@ -109,13 +129,24 @@ func ExecuteContext(ctx context.Context, options CommandOptions) int {
 }
 
 type parsedArgs struct {
+	command               string
 	token                 stringOption
+	githubAPI             stringOption
+	gitBackend            stringOption
+	appID                 stringOption
+	appPrivateKey         stringOption
+	appInstallationID     stringOption
 	title                 stringOption
 	productionBranch      stringOption
 	stagingBranch         stringOption
 	templatePath          stringOption
+	stages                stringSliceOption
 	labels                stringSliceOption
 	reviewers             stringSliceOption
+	categories            stringOption
+	excludeLabels         stringSliceOption
+	includePaths          stringSliceOption
+	excludePaths          stringSliceOption
 	mention               stringOption
 	assignPRAuthor        boolOption
 	requestPRAuthorReview boolOption
@@ -123,7 +154,31 @@ type parsedArgs struct {
 	json                  boolOption
 	noFetch               boolOption
 	squashed              boolOption
+	rebased               boolOption
+	squashDetection       stringOption
+	prLookup              stringOption
 	overwriteDescription  boolOption
+	publish               boolOption
+	releaseTemplatePath   stringOption
+	tagPattern            stringOption
+	draft                 boolOption
+	prerelease            boolOption
+	changelog             boolOption
+	changelogPath         stringOption
+	changelogTemplatePath stringOption
+	changelogCommit       boolOption
+	check                 boolOption
+	finalize              boolOption
+	mergeMethod           stringOption
+	autoMerge             boolOption
+	cacheDir              stringOption
+	cacheMaxSize          stringOption
+	noCache               boolOption
+	maxRetries            stringOption
+	retryBaseDelay        stringOption
+	retryMaxDelay         stringOption
+	color                 stringOption
+	profile               stringOption
 	verbose               boolOption
 	version               boolOption
 }
@@ -134,11 +189,23 @@ func parseArgs(args []string, stderr io.Writer) (parsedArgs, error) {
 	flagSet := flag.NewFlagSet("go-pr-release", flag.ContinueOnError)
 	flagSet.SetOutput(stderr)
 	flagSet.Usage = func() {
-		fmt.Fprintln(stderr, "Usage: go-pr-release [options]")
+		fmt.Fprintln(stderr, "Usage: go-pr-release [command] [options]")
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Commands:")
+		for _, command := range commands {
+			fmt.Fprintf(stderr, "  %-10s %s\n", command.name, command.usage)
+		}
+		fmt.Fprintln(stderr)
+		fmt.Fprintln(stderr, "Options:")
 		flagSet.PrintDefaults()
 	}
 
 	flagSet.Var(&parsed.token, "token", "GitHub API token")
+	flagSet.Var(&parsed.githubAPI, "github-api", "GitHub API to use (rest, graphql)")
+	flagSet.Var(&parsed.gitBackend, "git-backend", "Git implementation to use (exec, go-git)")
+	flagSet.Var(&parsed.appID, "app-id", "GitHub App ID to authenticate as")
+	flagSet.Var(&parsed.appPrivateKey, "app-private-key", "GitHub App private key path or PEM")
+	flagSet.Var(&parsed.appInstallationID, "app-installation-id", "GitHub App installation ID (discovered from the repository when omitted)")
 	flagSet.Var(&parsed.title, "title", "Release pull request title")
 
 	flagSet.Var(&parsed.productionBranch, "production-branch", "Production branch")
@@ -152,12 +219,19 @@ func parseArgs(args []string, stderr io.Writer) (parsedArgs, error) {
 	flagSet.Var(&parsed.templatePath, "template", "Template file path")
 	flagSet.Var(&parsed.templatePath, "t", "Template file path")
 
+	flagSet.Var(&parsed.stages, "stages", "Ordered promotion stages (from:to pairs or names of pr-release.stage.<name> sections)")
+
 	flagSet.Var(&parsed.labels, "label", "Labels to add")
 	flagSet.Var(&parsed.labels, "l", "Labels to add")
 
 	flagSet.Var(&parsed.reviewers, "reviewer", "Reviewers to request")
 	flagSet.Var(&parsed.reviewers, "r", "Reviewers to request")
 
+	flagSet.Var(&parsed.categories, "categories", "Release note categories (Title:label1,label2;Title:label3)")
+	flagSet.Var(&parsed.excludeLabels, "exclude-labels", "Labels that hide pull requests from the release PR body")
+	flagSet.Var(&parsed.includePaths, "include-path", "Only release pull requests touching these path globs")
+	flagSet.Var(&parsed.excludePaths, "exclude-path", "Ignore files matching these path globs")
+
 	flagSet.Var(&parsed.mention, "mention", "Mention target (author)")
 	flagSet.Var(&parsed.assignPRAuthor, "assign-pr-author", "Assign PR authors to the release PR")
 	flagSet.Var(&parsed.requestPRAuthorReview, "request-pr-author-review", "Request review from PR authors")
@@ -167,7 +241,31 @@ func parseArgs(args []string, stderr io.Writer) (parsedArgs, error) {
 	flagSet.Var(&parsed.json, "json", "Print release payload as JSON")
 	flagSet.Var(&parsed.noFetch, "no-fetch", "Do not update origin before inspection")
 	flagSet.Var(&parsed.squashed, "squashed", "Include squash merged pull requests")
+	flagSet.Var(&parsed.publish, "publish", "Tag and release the most recently merged release PR")
+	flagSet.Var(&parsed.releaseTemplatePath, "release-template", "Release notes template path for --publish")
+	flagSet.Var(&parsed.tagPattern, "tag-pattern", "Tag name template for --publish")
+	flagSet.Var(&parsed.draft, "draft", "Publish the release as a draft")
+	flagSet.Var(&parsed.prerelease, "prerelease", "Mark the release as a prerelease")
+	flagSet.Var(&parsed.changelog, "changelog", "Add the pending release to the changelog")
+	flagSet.Var(&parsed.changelogPath, "changelog-path", "Changelog path relative to the repository root")
+	flagSet.Var(&parsed.changelogTemplatePath, "changelog-template", "Changelog section template path for --changelog")
+	flagSet.Var(&parsed.changelogCommit, "changelog-commit", "Commit the changelog to the staging branch through the API")
+	flagSet.Var(&parsed.check, "check", "Report the checklist of the release PR without merging it")
+	flagSet.Var(&parsed.finalize, "finalize", "Merge the release PR once every checklist item is checked")
+	flagSet.Var(&parsed.mergeMethod, "merge-method", "Merge method for --finalize (merge, squash, rebase)")
+	flagSet.Var(&parsed.autoMerge, "auto-merge", "Enable auto-merge instead of merging with --finalize")
+	flagSet.Var(&parsed.rebased, "rebased", "Include rebase merged pull requests")
+	flagSet.Var(&parsed.squashDetection, "squash-detection", "Squash merge detection strategy (search, subject)")
+	flagSet.Var(&parsed.prLookup, "pr-lookup", "Pull request lookup strategy (scan, commits)")
 	flagSet.Var(&parsed.overwriteDescription, "overwrite-description", "Overwrite the release PR description instead of merging checklists")
+	flagSet.Var(&parsed.cacheDir, "cache-dir", "Directory for caching GET responses between runs")
+	flagSet.Var(&parsed.cacheMaxSize, "cache-max-size", "Maximum cache size (e.g. 512K, 100M, 1G)")
+	flagSet.Var(&parsed.noCache, "no-cache", "Disable the HTTP response cache")
+	flagSet.Var(&parsed.maxRetries, "max-retries", "Maximum retries for rate limited, 5xx, and network failures")
+	flagSet.Var(&parsed.retryBaseDelay, "retry-base-delay", "Initial retry backoff (e.g. 1s)")
+	flagSet.Var(&parsed.retryMaxDelay, "retry-max-delay", "Maximum retry backoff (e.g. 30s)")
+	flagSet.Var(&parsed.profile, "profile", "Configuration profile to apply over the base configuration")
+	flagSet.Var(&parsed.color, "color", "Color the preview diff (auto, always, never)")
 	flagSet.Var(&parsed.verbose, "verbose", "Print verbose logs")
 	flagSet.Var(&parsed.version, "version", "Print version")
 	flagSet.Var(&parsed.version, "v", "Print version")
@@ -175,6 +273,16 @@ func parseArgs(args []string, stderr io.Writer) (parsedArgs, error) {
 	if err := flagSet.Parse(args); err != nil {
 		return parsed, err
 	}
+	// The command may come before or after the options.
+	if flagSet.NArg() > 0 {
+		parsed.command = flagSet.Arg(0)
+		if !isCommand(parsed.command) {
+			return parsed, fmt.Errorf("unknown command %q", parsed.command)
+		}
+		if err := flagSet.Parse(flagSet.Args()[1:]); err != nil {
+			return parsed, err
+		}
+	}
 	if flagSet.NArg() > 0 {
 		return parsed, fmt.Errorf("unexpected arguments: %s", strings.Join(flagSet.Args(), " "))
 	}
@@ -182,193 +290,694 @@ func parseArgs(args []string, stderr io.Writer) (parsedArgs, error) {
 	return parsed, nil
 }
 
+var commands = []struct {
+	name  string
+	usage string
+}{
+	{name: release.CommandRun, usage: "Create or update the release PR (default)"},
+	{name: release.CommandPreview, usage: "Print the release PR title and body and the diff against the open one"},
+	{name: release.CommandStatus, usage: "Show the open release PR, its checklist and pending PRs"},
+	{name: release.CommandDoctor, usage: "Check the token, remote, branches and templates"},
+	{name: "publish", usage: "Same as --publish"},
+	{name: "changelog", usage: "Same as --changelog"},
+	{name: "check", usage: "Same as --check"},
+	{name: "finalize", usage: "Same as --finalize"},
+}
+
+func isCommand(name string) bool {
+	for _, command := range commands {
+		if command.name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func resolveConfig(
 	ctx context.Context,
 	workDir string,
 	lookupEnv func(string) (string, bool),
 	args parsedArgs,
 ) (release.Config, error) {
-	git := release.NewGit(workDir)
+	return (&configResolver{lookupEnv: lookupEnv}).resolve(ctx, workDir, args)
+}
+
+func (r *configResolver) resolve(ctx context.Context, workDir string, args parsedArgs) (release.Config, error) {
+	// The backend decides how git config is read, so it cannot come from git config itself.
+	gitBackend, err := r.pickString(args.gitBackend, "", []string{"GIT_PR_RELEASE_GIT_BACKEND"}, release.GitBackendExec)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch gitBackend {
+	case release.GitBackendExec, release.GitBackendGoGit:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported git backend %q (exec, go-git)", gitBackend)
+	}
+
+	git := release.NewGitBackend(release.Config{WorkDir: workDir, GitBackend: gitBackend})
 	repository, err := git.ResolveRemote(ctx, release.DefaultRemoteName)
 	if err != nil {
 		return release.Config{}, err
 	}
 
-	gitString := func(key string) (string, error) {
-		value, ok, err := git.LookupProjectConfig(ctx, repository, key)
-		if err != nil || !ok {
-			return "", err
-		}
-		return strings.TrimSpace(value), nil
+	root, err := git.Root(ctx)
+	if err != nil {
+		return release.Config{}, err
+	}
+	r.file, err = loadConfigFile(root)
+	if err != nil {
+		return release.Config{}, err
+	}
+	r.gitConfig = func(key string) (string, bool, error) {
+		return git.LookupProjectConfig(ctx, repository, key)
 	}
 
-	gitBool := func(key string) (bool, bool, error) {
-		value, err := gitString(key)
-		if err != nil || value == "" {
-			return false, false, err
+	profile, err := r.pickString(args.profile, "", []string{"GIT_PR_RELEASE_PROFILE"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	if profile != "" {
+		projectKeys, err := git.ProjectConfigKeys(ctx)
+		if err != nil {
+			return release.Config{}, err
 		}
-		parsedValue, parseErr := strconv.ParseBool(value)
-		if parseErr != nil {
-			return false, false, fmt.Errorf("parse git config %s: %w", key, parseErr)
+		r.profiles, err = r.profileChain(profile, projectKeys)
+		if err != nil {
+			return release.Config{}, err
 		}
-		return parsedValue, true, nil
 	}
 
 	config := release.Config{
 		WorkDir:    workDir,
 		RemoteName: release.DefaultRemoteName,
 		Repository: repository,
+		GitBackend: gitBackend,
 	}
 
-	config.Token, err = pickString(args.token, lookupEnv, gitString, "token", []string{"GIT_PR_RELEASE_TOKEN", "GO_PR_RELEASE_TOKEN"}, "")
+	config.Token, err = r.pickString(args.token, "token", []string{"GIT_PR_RELEASE_TOKEN", "GO_PR_RELEASE_TOKEN"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	if config.Token == "" && r.fallbackToken != "" {
+		config.Token = r.fallbackToken
+		r.record("token", config.Token, "GITHUB_TOKEN")
+	}
+	config.AppID, err = r.pickInt64(args.appID, "app-id", []string{"GIT_PR_RELEASE_APP_ID"})
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.AppPrivateKey, err = r.pickString(args.appPrivateKey, "app-private-key", []string{"GIT_PR_RELEASE_APP_PRIVATE_KEY"}, "")
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.Title, err = pickString(args.title, lookupEnv, gitString, "", []string{"GIT_PR_RELEASE_TITLE", "GO_PR_RELEASE_TITLE"}, "")
+	config.AppInstallationID, err = r.pickInt64(args.appInstallationID, "app-installation-id", []string{"GIT_PR_RELEASE_APP_INSTALLATION_ID"})
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.ProductionBranch, err = pickString(args.productionBranch, lookupEnv, gitString, "branch.production", []string{"GIT_PR_RELEASE_BRANCH_PRODUCTION", "GO_PR_RELEASE_RELEASE"}, "master")
+	config.GitHubAPI, err = r.pickString(args.githubAPI, "github-api", []string{"GIT_PR_RELEASE_GITHUB_API"}, release.GitHubAPIREST)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch config.GitHubAPI {
+	case release.GitHubAPIREST, release.GitHubAPIGraphQL:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported github api %q (rest, graphql)", config.GitHubAPI)
+	}
+	config.Title, err = r.pickString(args.title, "", []string{"GIT_PR_RELEASE_TITLE", "GO_PR_RELEASE_TITLE"}, "")
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.StagingBranch, err = pickString(args.stagingBranch, lookupEnv, gitString, "branch.staging", []string{"GIT_PR_RELEASE_BRANCH_STAGING", "GO_PR_RELEASE_DEVELOP"}, "staging")
+	config.ProductionBranch, err = r.pickString(args.productionBranch, "branch.production", []string{"GIT_PR_RELEASE_BRANCH_PRODUCTION", "GO_PR_RELEASE_RELEASE"}, "master")
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.TemplatePath, err = pickString(args.templatePath, lookupEnv, gitString, "template", []string{"GIT_PR_RELEASE_TEMPLATE", "GO_PR_RELEASE_TEMPLATE"}, "")
+	config.StagingBranch, err = r.pickString(args.stagingBranch, "branch.staging", []string{"GIT_PR_RELEASE_BRANCH_STAGING", "GO_PR_RELEASE_DEVELOP"}, "staging")
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.Mention, err = pickString(args.mention, lookupEnv, gitString, "mention", []string{"GIT_PR_RELEASE_MENTION"}, "")
+	config.TemplatePath, err = r.pickString(args.templatePath, "template", []string{"GIT_PR_RELEASE_TEMPLATE", "GO_PR_RELEASE_TEMPLATE"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Mention, err = r.pickString(args.mention, "mention", []string{"GIT_PR_RELEASE_MENTION"}, "")
 	if err != nil {
 		return release.Config{}, err
 	}
 
-	config.Labels, err = pickStringSlice(args.labels, lookupEnv, gitString, "labels", []string{"GIT_PR_RELEASE_LABELS", "GO_PR_RELEASE_LABELS"})
+	config.Labels, err = r.pickStringSlice(args.labels, "labels", []string{"GIT_PR_RELEASE_LABELS", "GO_PR_RELEASE_LABELS"})
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.ExtraReviewers, err = pickStringSlice(args.reviewers, lookupEnv, gitString, "", []string{"GIT_PR_RELEASE_REVIEWERS", "GO_PR_RELEASE_REVIEWERS"})
+	config.ExtraReviewers, err = r.pickStringSlice(args.reviewers, "", []string{"GIT_PR_RELEASE_REVIEWERS", "GO_PR_RELEASE_REVIEWERS"})
 	if err != nil {
 		return release.Config{}, err
 	}
 
-	config.AssignPRAuthor, err = pickBool(args.assignPRAuthor, lookupEnv, gitBool, "assign-pr-author", []string{"GIT_PR_RELEASE_ASSIGN_PR_AUTHOR"}, false)
+	categorySpec, err := r.pickString(args.categories, "categories", []string{"GIT_PR_RELEASE_CATEGORIES"}, "")
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.RequestPRAuthorReview, err = pickBool(args.requestPRAuthorReview, lookupEnv, gitBool, "request-pr-author-review", []string{"GIT_PR_RELEASE_REQUEST_PR_AUTHOR_REVIEW"}, false)
+	config.Categories, err = parseCategories(categorySpec)
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.DryRun, err = pickBool(args.dryRun, lookupEnv, gitBool, "", []string{"GIT_PR_RELEASE_DRY_RUN", "GO_PR_RELEASE_DRY_RUN"}, false)
+	config.ExcludeLabels, err = r.pickStringSlice(args.excludeLabels, "exclude-labels", []string{"GIT_PR_RELEASE_EXCLUDE_LABELS"})
 	if err != nil {
 		return release.Config{}, err
 	}
-	config.InsecureSkipTLSVerify, err = pickBool(boolOption{}, lookupEnv, gitBool, "ssl-no-verify", []string{"GIT_PR_RELEASE_SSL_NO_VERIFY"}, false)
+
+	config.IncludePaths, err = r.pickStringSlice(args.includePaths, "paths.include", []string{"GIT_PR_RELEASE_INCLUDE_PATHS"})
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.ExcludePaths, err = r.pickStringSlice(args.excludePaths, "paths.exclude", []string{"GIT_PR_RELEASE_EXCLUDE_PATHS"})
 	if err != nil {
 		return release.Config{}, err
 	}
 
+	config.AssignPRAuthor, err = r.pickBool(args.assignPRAuthor, "assign-pr-author", []string{"GIT_PR_RELEASE_ASSIGN_PR_AUTHOR"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.RequestPRAuthorReview, err = r.pickBool(args.requestPRAuthorReview, "request-pr-author-review", []string{"GIT_PR_RELEASE_REQUEST_PR_AUTHOR_REVIEW"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.DryRun, err = r.pickBool(args.dryRun, "", []string{"GIT_PR_RELEASE_DRY_RUN", "GO_PR_RELEASE_DRY_RUN"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.InsecureSkipTLSVerify, err = r.pickBool(boolOption{}, "ssl-no-verify", []string{"GIT_PR_RELEASE_SSL_NO_VERIFY"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	stageSpecs, err := r.pickStringSlice(args.stages, "stages", []string{"GIT_PR_RELEASE_STAGES"})
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Stages, err = resolveStages(stageSpecs, r.stageValue)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.Publish, err = r.pickBool(args.publish, "", []string{"GIT_PR_RELEASE_PUBLISH"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.ReleaseTemplatePath, err = r.pickString(args.releaseTemplatePath, "publish.template", []string{"GIT_PR_RELEASE_RELEASE_TEMPLATE"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.TagPattern, err = r.pickString(args.tagPattern, "publish.tag", []string{"GIT_PR_RELEASE_TAG_PATTERN"}, release.DefaultTagPattern)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Draft, err = r.pickBool(args.draft, "publish.draft", []string{"GIT_PR_RELEASE_DRAFT"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Prerelease, err = r.pickBool(args.prerelease, "publish.prerelease", []string{"GIT_PR_RELEASE_PRERELEASE"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.Changelog, err = r.pickBool(args.changelog, "", []string{"GIT_PR_RELEASE_CHANGELOG"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.ChangelogPath, err = r.pickString(args.changelogPath, "changelog.path", []string{"GIT_PR_RELEASE_CHANGELOG_PATH"}, release.DefaultChangelogPath)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.ChangelogTemplatePath, err = r.pickString(args.changelogTemplatePath, "changelog.template", []string{"GIT_PR_RELEASE_CHANGELOG_TEMPLATE"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.ChangelogCommit, err = r.pickBool(args.changelogCommit, "changelog.commit", []string{"GIT_PR_RELEASE_CHANGELOG_COMMIT"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.Check, err = r.pickBool(args.check, "", []string{"GIT_PR_RELEASE_CHECK"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Finalize, err = r.pickBool(args.finalize, "", []string{"GIT_PR_RELEASE_FINALIZE"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.MergeMethod, err = r.pickString(args.mergeMethod, "finalize.merge-method", []string{"GIT_PR_RELEASE_MERGE_METHOD"}, release.MergeMethodMerge)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch config.MergeMethod {
+	case release.MergeMethodMerge, release.MergeMethodSquash, release.MergeMethodRebase:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported merge method %q (merge, squash, rebase)", config.MergeMethod)
+	}
+	config.AutoMerge, err = r.pickBool(args.autoMerge, "finalize.auto-merge", []string{"GIT_PR_RELEASE_AUTO_MERGE"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.Command = release.CommandRun
+	switch args.command {
+	case "", release.CommandRun:
+	case "publish":
+		config.Publish = true
+	case "changelog":
+		config.Changelog = true
+	case "check":
+		config.Check = true
+	case "finalize":
+		config.Finalize = true
+	default:
+		config.Command = args.command
+	}
+
+	modes := 0
+	for _, enabled := range []bool{config.Publish, config.Changelog, config.Check, config.Finalize, config.Command != release.CommandRun} {
+		if enabled {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return release.Config{}, errors.New("only one of the preview, status, doctor, publish, changelog, check and finalize commands can be given")
+	}
+
+	config.Rebased, err = r.pickBool(args.rebased, "rebased", []string{"GIT_PR_RELEASE_REBASED"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.SquashDetection, err = r.pickString(args.squashDetection, "squash-detection", []string{"GIT_PR_RELEASE_SQUASH_DETECTION"}, release.SquashDetectionSearch)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch config.SquashDetection {
+	case release.SquashDetectionSearch, release.SquashDetectionSubject:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported squash detection %q (search, subject)", config.SquashDetection)
+	}
+
+	config.PullRequestLookup, err = r.pickString(args.prLookup, "pr-lookup", []string{"GIT_PR_RELEASE_PR_LOOKUP"}, release.PullRequestLookupScan)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch config.PullRequestLookup {
+	case release.PullRequestLookupScan, release.PullRequestLookupCommits:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported pr lookup %q (scan, commits)", config.PullRequestLookup)
+	}
+	// The commits lookup maps squash commits to pull requests itself and
+	// never reads commit subjects.
+	if config.PullRequestLookup == release.PullRequestLookupCommits && config.SquashDetection == release.SquashDetectionSubject {
+		return release.Config{}, errors.New("squash detection subject cannot be combined with pr lookup commits")
+	}
+
+	config.CacheDir, err = r.pickString(args.cacheDir, "cache.dir", []string{"GIT_PR_RELEASE_CACHE_DIR"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	cacheMaxSize, err := r.pickString(args.cacheMaxSize, "cache.max-size", []string{"GIT_PR_RELEASE_CACHE_MAX_SIZE"}, "")
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.CacheMaxBytes, err = parseByteSize(cacheMaxSize)
+	if err != nil {
+		return release.Config{}, fmt.Errorf("parse cache max size: %w", err)
+	}
+	config.NoCache, err = r.pickBool(args.noCache, "", []string{"GIT_PR_RELEASE_NO_CACHE"}, false)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	maxRetries, err := r.pickString(args.maxRetries, "retry.max-retries", []string{"GIT_PR_RELEASE_MAX_RETRIES"}, strconv.Itoa(release.DefaultMaxRetries))
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Retry.MaxRetries, err = strconv.Atoi(maxRetries)
+	if err != nil || config.Retry.MaxRetries < 0 {
+		return release.Config{}, fmt.Errorf("invalid max retries %q", maxRetries)
+	}
+	config.Retry.BaseDelay, err = r.pickDuration(args.retryBaseDelay, "retry.base-delay", []string{"GIT_PR_RELEASE_RETRY_BASE_DELAY"}, release.DefaultRetryBaseDelay)
+	if err != nil {
+		return release.Config{}, err
+	}
+	config.Retry.MaxDelay, err = r.pickDuration(args.retryMaxDelay, "retry.max-delay", []string{"GIT_PR_RELEASE_RETRY_MAX_DELAY"}, release.DefaultRetryMaxDelay)
+	if err != nil {
+		return release.Config{}, err
+	}
+
+	config.Color, err = r.pickString(args.color, "color", []string{"GIT_PR_RELEASE_COLOR"}, release.ColorAuto)
+	if err != nil {
+		return release.Config{}, err
+	}
+	switch config.Color {
+	case release.ColorAuto:
+		// https://no-color.org/
+		if value, ok := r.lookupEnv("NO_COLOR"); ok && value != "" {
+			config.Color = release.ColorNever
+			r.record("color", config.Color, "env NO_COLOR")
+		}
+	case release.ColorAlways, release.ColorNever:
+	default:
+		return release.Config{}, fmt.Errorf("unsupported color %q (auto, always, never)", config.Color)
+	}
+
+	// https://docs.github.com/actions/learn-github-actions/variables#default-environment-variables
+	if value, _ := r.lookupEnv("GITHUB_ACTIONS"); value == "true" {
+		config.Actions.Enabled = true
+		config.Actions.OutputPath, _ = r.lookupEnv("GITHUB_OUTPUT")
+		config.Actions.StepSummaryPath, _ = r.lookupEnv("GITHUB_STEP_SUMMARY")
+	}
+
 	config.JSON = args.json.value
 	config.NoFetch = args.noFetch.value
 	config.Squashed = args.squashed.value
 	config.OverwriteDescription = args.overwriteDescription.value
 	config.Verbose = args.verbose.value
 
-	if config.Token == "" {
+	if config.AppID != 0 && config.AppPrivateKey == "" {
+		return release.Config{}, errors.New("app private key is required with app id (--app-private-key, GIT_PR_RELEASE_APP_PRIVATE_KEY, or pr-release.app-private-key)")
+	}
+	if config.Token == "" && config.AppID == 0 {
 		return release.Config{}, errors.New("token is required (--token, GIT_PR_RELEASE_TOKEN, GO_PR_RELEASE_TOKEN, or pr-release.token)")
 	}
 
 	return config, nil
 }
 
-func pickString(
+// resolveStages accepts "from:to" shorthands and names of stages described by
+// pr-release.stage.<name>.* keys.
+func resolveStages(specs []string, gitConfig func(string) (string, error)) ([]release.Stage, error) {
+	stages := make([]release.Stage, 0, len(specs))
+	for _, spec := range specs {
+		if from, to, ok := strings.Cut(spec, ":"); ok {
+			from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+			if from == "" || to == "" {
+				return nil, fmt.Errorf("invalid stage %q (want from:to)", spec)
+			}
+			stages = append(stages, release.Stage{StagingBranch: from, ProductionBranch: to})
+			continue
+		}
+
+		stage := release.Stage{Name: spec}
+		prefix := "stage." + spec + "."
+		for key, target := range map[string]*string{
+			"from":     &stage.StagingBranch,
+			"to":       &stage.ProductionBranch,
+			"template": &stage.TemplatePath,
+			"title":    &stage.Title,
+		} {
+			value, err := gitConfig(prefix + key)
+			if err != nil {
+				return nil, err
+			}
+			*target = value
+		}
+		for key, target := range map[string]*[]string{
+			"labels":        &stage.Labels,
+			"reviewers":     &stage.ExtraReviewers,
+			"paths.include": &stage.IncludePaths,
+			"paths.exclude": &stage.ExcludePaths,
+		} {
+			value, err := gitConfig(prefix + key)
+			if err != nil {
+				return nil, err
+			}
+			if value != "" {
+				*target = splitCommaSeparated(value)
+			}
+		}
+		if stage.StagingBranch == "" || stage.ProductionBranch == "" {
+			return nil, fmt.Errorf("stage %q requires pr-release.%sfrom and pr-release.%sto", spec, prefix, prefix)
+		}
+		stages = append(stages, stage)
+	}
+	return stages, nil
+}
+
+// parseCategories reads "Features:feature,enhancement;Bug fixes:bug".
+func parseCategories(spec string) ([]release.Category, error) {
+	var categories []release.Category
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		title, labels, ok := strings.Cut(entry, ":")
+		title = strings.TrimSpace(title)
+		if !ok || title == "" {
+			return nil, fmt.Errorf("invalid category %q (want Title:label1,label2)", entry)
+		}
+		category := release.Category{Title: title, Labels: splitCommaSeparated(labels)}
+		if len(category.Labels) == 0 {
+			return nil, fmt.Errorf("category %q has no labels", title)
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// configResolver looks each setting up in flag, environment, configuration
+// file, git config and default order and records where it was found.
+type configResolver struct {
+	lookupEnv     func(string) (string, bool)
+	file          *configFile
+	gitConfig     func(string) (string, bool, error)
+	profiles      []string
+	sources       []configSource
+	fallbackToken string
+}
+
+type configSource struct {
+	name   string
+	value  string
+	source string
+}
+
+func (r *configResolver) record(name, value, source string) {
+	if (name == "token" || name == "app-private-key") && value != "" {
+		value = "***"
+	}
+	for i := range r.sources {
+		if r.sources[i].name == name {
+			r.sources[i] = configSource{name: name, value: value, source: source}
+			return
+		}
+	}
+	r.sources = append(r.sources, configSource{name: name, value: value, source: source})
+}
+
+// lookup reads key from the selected profiles and then from the base
+// configuration, preferring the configuration file over git config at each
+// level. The source is empty when none of them sets it.
+func (r *configResolver) lookup(key string) (string, string, error) {
+	for _, profile := range r.profiles {
+		if value, ok := r.file.lookupProfile(profile, key); ok {
+			return value.value, fmt.Sprintf("%s:%d", r.file.path, value.line), nil
+		}
+		value, source, err := r.lookupGit(profile + "." + key)
+		if err != nil || source != "" {
+			return value, source, err
+		}
+	}
+	if value, ok := r.file.lookup(key); ok {
+		return value.value, fmt.Sprintf("%s:%d", r.file.path, value.line), nil
+	}
+	return r.lookupGit(key)
+}
+
+func (r *configResolver) lookupGit(key string) (string, string, error) {
+	if r.gitConfig == nil {
+		return "", "", nil
+	}
+	value, ok, err := r.gitConfig(key)
+	if err != nil || !ok {
+		return "", "", err
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", "", nil
+	}
+	return value, "git config pr-release." + key, nil
+}
+
+func (r *configResolver) lookupSetting(gitKey string, envKeys []string) (string, string, error) {
+	for _, key := range envKeys {
+		if value, ok := r.lookupEnv(key); ok {
+			return value, "env " + key, nil
+		}
+	}
+	if gitKey == "" {
+		return "", "", nil
+	}
+	return r.lookup(gitKey)
+}
+
+func (r *configResolver) stageValue(key string) (string, error) {
+	value, source, err := r.lookup(key)
+	if err != nil || source == "" {
+		return "", err
+	}
+	r.record(key, value, source)
+	return value, nil
+}
+
+// settingName names a setting after its git config key, or after its
+// environment variable when it has none.
+func settingName(gitKey string, envKeys []string) string {
+	if gitKey != "" || len(envKeys) == 0 {
+		return gitKey
+	}
+	name := strings.TrimPrefix(envKeys[0], "GIT_PR_RELEASE_")
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+func (r *configResolver) pickString(
 	option stringOption,
-	lookupEnv func(string) (string, bool),
-	gitConfig func(string) (string, error),
 	gitKey string,
 	envKeys []string,
 	defaultValue string,
 ) (string, error) {
+	name := settingName(gitKey, envKeys)
 	if option.set {
-		return strings.TrimSpace(option.value), nil
+		value := strings.TrimSpace(option.value)
+		r.record(name, value, "flag")
+		return value, nil
 	}
-	for _, key := range envKeys {
-		if value, ok := lookupEnv(key); ok {
-			return strings.TrimSpace(value), nil
-		}
+	value, source, err := r.lookupSetting(gitKey, envKeys)
+	if err != nil {
+		return "", err
 	}
-	if gitKey != "" {
-		value, err := gitConfig(gitKey)
-		if err != nil {
-			return "", err
-		}
-		if value != "" {
-			return value, nil
-		}
+	if source == "" {
+		r.record(name, defaultValue, "default")
+		return defaultValue, nil
 	}
-	return defaultValue, nil
+	value = strings.TrimSpace(value)
+	r.record(name, value, source)
+	return value, nil
 }
 
-func pickStringSlice(
+func (r *configResolver) pickInt64(
+	option stringOption,
+	gitKey string,
+	envKeys []string,
+) (int64, error) {
+	value, err := r.pickString(option, gitKey, envKeys, "")
+	if err != nil || value == "" {
+		return 0, err
+	}
+	parsedValue, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", gitKey, err)
+	}
+	return parsedValue, nil
+}
+
+func (r *configResolver) pickDuration(
+	option stringOption,
+	gitKey string,
+	envKeys []string,
+	defaultValue time.Duration,
+) (time.Duration, error) {
+	value, err := r.pickString(option, gitKey, envKeys, "")
+	if err != nil || value == "" {
+		return defaultValue, err
+	}
+	parsedValue, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", gitKey, err)
+	}
+	if parsedValue <= 0 {
+		return 0, fmt.Errorf("parse %s: duration must be positive", gitKey)
+	}
+	return parsedValue, nil
+}
+
+func (r *configResolver) pickStringSlice(
 	option stringSliceOption,
-	lookupEnv func(string) (string, bool),
-	gitConfig func(string) (string, error),
 	gitKey string,
 	envKeys []string,
 ) ([]string, error) {
+	name := settingName(gitKey, envKeys)
 	if option.set {
+		r.record(name, strings.Join(option.values, ","), "flag")
 		return option.values, nil
 	}
-	for _, key := range envKeys {
-		if value, ok := lookupEnv(key); ok {
-			return splitCommaSeparated(value), nil
-		}
+	value, source, err := r.lookupSetting(gitKey, envKeys)
+	if err != nil {
+		return nil, err
 	}
-	if gitKey != "" {
-		value, err := gitConfig(gitKey)
-		if err != nil {
-			return nil, err
-		}
-		if value != "" {
-			return splitCommaSeparated(value), nil
-		}
+	if source == "" {
+		r.record(name, "", "default")
+		return nil, nil
 	}
-	return nil, nil
+	values := splitCommaSeparated(value)
+	r.record(name, strings.Join(values, ","), source)
+	return values, nil
 }
 
-func pickBool(
+func (r *configResolver) pickBool(
 	option boolOption,
-	lookupEnv func(string) (string, bool),
-	gitConfig func(string) (bool, bool, error),
 	gitKey string,
 	envKeys []string,
 	defaultValue bool,
 ) (bool, error) {
+	name := settingName(gitKey, envKeys)
 	if option.set {
+		r.record(name, strconv.FormatBool(option.value), "flag")
 		return option.value, nil
 	}
-	for _, key := range envKeys {
-		if value, ok := lookupEnv(key); ok {
-			parsedValue, err := strconv.ParseBool(strings.TrimSpace(value))
-			if err != nil {
-				return false, fmt.Errorf("parse environment variable %s: %w", key, err)
-			}
-			return parsedValue, nil
-		}
+	value, source, err := r.lookupSetting(gitKey, envKeys)
+	if err != nil {
+		return false, err
 	}
-	if gitKey != "" {
-		value, ok, err := gitConfig(gitKey)
-		if err != nil {
-			return false, err
-		}
-		if ok {
-			return value, nil
+	if source == "" {
+		r.record(name, strconv.FormatBool(defaultValue), "default")
+		return defaultValue, nil
+	}
+	parsedValue, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		if strings.HasPrefix(source, "env ") {
+			return false, fmt.Errorf("parse environment variable %s: %w", strings.TrimPrefix(source, "env "), err)
 		}
+		return false, fmt.Errorf("parse %s: %w", source, err)
+	}
+	r.record(name, strconv.FormatBool(parsedValue), source)
+	return parsedValue, nil
+}
+
+func parseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, nil
+	}
+	value = strings.TrimSuffix(value, "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if size <= 0 {
+		return 0, fmt.Errorf("size must be positive")
 	}
-	return defaultValue, nil
+	return size * multiplier, nil
 }
 
 func splitCommaSeparated(value string) []string {
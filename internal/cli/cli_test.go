@@ -3,10 +3,12 @@ package cli
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -40,6 +42,54 @@ func TestExecuteContextVersion(t *testing.T) {
 	}
 }
 
+func TestExecuteContextSelectsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantExit    int
+		wantCommand string
+		wantPublish bool
+	}{
+		{name: "no command", args: []string{"--token", "dummy"}, wantCommand: release.CommandRun},
+		{name: "command first", args: []string{"preview", "--token", "dummy"}, wantCommand: release.CommandPreview},
+		{name: "command after options", args: []string{"--token", "dummy", "doctor", "--json"}, wantCommand: release.CommandDoctor},
+		{name: "mode command", args: []string{"publish", "--token", "dummy"}, wantCommand: release.CommandRun, wantPublish: true},
+		{name: "unknown command", args: []string{"deploy"}, wantExit: 2},
+		{name: "extra arguments", args: []string{"status", "main"}, wantExit: 2},
+		{name: "two modes", args: []string{"status", "--publish", "--token", "dummy"}, wantExit: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workDir := initGitRepository(t, "git@github.com:octo/example.git")
+			var got release.Config
+			exitCode := ExecuteContext(context.Background(), CommandOptions{
+				Args:      tt.args,
+				WorkDir:   workDir,
+				LookupEnv: func(string) (string, bool) { return "", false },
+				NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+					got = config
+					return stubService{}
+				},
+			})
+
+			if exitCode != tt.wantExit {
+				t.Fatalf("expected exit code %d, got %d", tt.wantExit, exitCode)
+			}
+			if tt.wantExit != 0 {
+				return
+			}
+			if got.Command != tt.wantCommand || got.Publish != tt.wantPublish {
+				t.Fatalf("unexpected config: command=%q publish=%v", got.Command, got.Publish)
+			}
+		})
+	}
+}
+
 func TestResolveConfigUsesLegacyEnvironmentVariables(t *testing.T) {
 	t.Parallel()
 
@@ -122,6 +172,307 @@ func TestResolveConfigReadsProjectAndHostAwareGitConfig(t *testing.T) {
 	}
 }
 
+func TestResolveConfigSelectsGitHubAPI(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":      "token",
+		"GIT_PR_RELEASE_GITHUB_API": "graphql",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.GitHubAPI != release.GitHubAPIGraphQL {
+		t.Fatalf("unexpected github api: %q", config.GitHubAPI)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":      "token",
+		"GIT_PR_RELEASE_GITHUB_API": "soap",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), "unsupported github api") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveConfigSelectsSquashDetection(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.SquashDetection != release.SquashDetectionSearch {
+		t.Fatalf("unexpected default squash detection: %q", config.SquashDetection)
+	}
+
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.squash-detection", "subject")
+	config, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.SquashDetection != release.SquashDetectionSubject {
+		t.Fatalf("unexpected squash detection: %q", config.SquashDetection)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":            "token",
+		"GIT_PR_RELEASE_SQUASH_DETECTION": "guess",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), "unsupported squash detection") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":     "token",
+		"GIT_PR_RELEASE_PR_LOOKUP": "commits",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), "squash detection subject cannot be combined with pr lookup commits") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveConfigReadsPathFilters(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.paths.include", "services/api")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.paths.exclude", "**/*.md")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !reflect.DeepEqual(config.IncludePaths, []string{"services/api"}) {
+		t.Fatalf("unexpected include paths: %v", config.IncludePaths)
+	}
+	if !reflect.DeepEqual(config.ExcludePaths, []string{"**/*.md"}) {
+		t.Fatalf("unexpected exclude paths: %v", config.ExcludePaths)
+	}
+
+	config, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":         "token",
+		"GIT_PR_RELEASE_INCLUDE_PATHS": "services/web,libs",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !reflect.DeepEqual(config.IncludePaths, []string{"services/web", "libs"}) {
+		t.Fatalf("unexpected include paths: %v", config.IncludePaths)
+	}
+}
+
+func TestResolveConfigReadsCategories(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.categories", "Features:feature,enhancement; Bug fixes:bug")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.exclude-labels", "skip-release-notes")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+
+	want := []release.Category{
+		{Title: "Features", Labels: []string{"feature", "enhancement"}},
+		{Title: "Bug fixes", Labels: []string{"bug"}},
+	}
+	if !reflect.DeepEqual(config.Categories, want) {
+		t.Fatalf("unexpected categories: %#v", config.Categories)
+	}
+	if !reflect.DeepEqual(config.ExcludeLabels, []string{"skip-release-notes"}) {
+		t.Fatalf("unexpected exclude labels: %v", config.ExcludeLabels)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":      "token",
+		"GIT_PR_RELEASE_CATEGORIES": "Features",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), "invalid category") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveConfigReadsPublishSettings(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.publish.template", ".github/release-notes.tmpl")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.publish.prerelease", "true")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{
+		publish: boolOption{value: true, set: true},
+		draft:   boolOption{value: true, set: true},
+	})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !config.Publish || !config.Draft || !config.Prerelease {
+		t.Fatalf("unexpected publish flags: publish=%v draft=%v prerelease=%v", config.Publish, config.Draft, config.Prerelease)
+	}
+	if config.ReleaseTemplatePath != ".github/release-notes.tmpl" {
+		t.Fatalf("unexpected release template: %q", config.ReleaseTemplatePath)
+	}
+	if config.TagPattern != release.DefaultTagPattern {
+		t.Fatalf("unexpected tag pattern: %q", config.TagPattern)
+	}
+}
+
+func TestResolveConfigReadsChangelogSettings(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.changelog.path", "docs/CHANGES.md")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.changelog.commit", "true")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":     "token",
+		"GIT_PR_RELEASE_CHANGELOG": "true",
+	}), parsedArgs{
+		changelogTemplatePath: stringOption{value: ".github/changelog.tmpl", set: true},
+	})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !config.Changelog || !config.ChangelogCommit {
+		t.Fatalf("unexpected changelog flags: changelog=%v commit=%v", config.Changelog, config.ChangelogCommit)
+	}
+	if config.ChangelogPath != "docs/CHANGES.md" || config.ChangelogTemplatePath != ".github/changelog.tmpl" {
+		t.Fatalf("unexpected changelog paths: %q %q", config.ChangelogPath, config.ChangelogTemplatePath)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{
+		publish:   boolOption{value: true, set: true},
+		changelog: boolOption{value: true, set: true},
+	})
+	if err == nil {
+		t.Fatal("expected --publish with --changelog to fail")
+	}
+}
+
+func TestResolveConfigSelectsGitBackend(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.branch.staging", "develop")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":       "token",
+		"GIT_PR_RELEASE_GIT_BACKEND": "go-git",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.GitBackend != release.GitBackendGoGit {
+		t.Fatalf("unexpected git backend: %q", config.GitBackend)
+	}
+	if config.Repository.FullName() != "octo/example" || config.StagingBranch != "develop" {
+		t.Fatalf("expected go-git backend to read remote and project config: %+v", config)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{gitBackend: stringOption{value: "libgit2", set: true}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported git backend") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestResolveConfigAcceptsGitHubAppInsteadOfToken(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.app-private-key", "/secrets/app.pem")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.app-installation-id", "99")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_APP_ID": "12345",
+	}), parsedArgs{appInstallationID: stringOption{value: "42", set: true}})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+
+	if config.AppID != 12345 {
+		t.Fatalf("unexpected app id: %d", config.AppID)
+	}
+	if config.AppPrivateKey != "/secrets/app.pem" {
+		t.Fatalf("unexpected app private key: %q", config.AppPrivateKey)
+	}
+	if config.AppInstallationID != 42 {
+		t.Fatalf("unexpected app installation id: %d", config.AppInstallationID)
+	}
+	if config.Token != "" {
+		t.Fatalf("unexpected token: %q", config.Token)
+	}
+}
+
+func TestResolveConfigReadsCacheSettings(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.cache.dir", "/tmp/pr-release-cache")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.cache.max-size", "64M")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.CacheDir != "/tmp/pr-release-cache" {
+		t.Fatalf("unexpected cache dir: %q", config.CacheDir)
+	}
+	if config.CacheMaxBytes != 64<<20 {
+		t.Fatalf("unexpected cache max bytes: %d", config.CacheMaxBytes)
+	}
+	if config.NoCache {
+		t.Fatalf("expected cache to be enabled")
+	}
+
+	config, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{noCache: boolOption{value: true, set: true}})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !config.NoCache {
+		t.Fatalf("expected --no-cache to disable the cache")
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":          "token",
+		"GIT_PR_RELEASE_CACHE_MAX_SIZE": "lots",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), "cache max size") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 func TestExecuteContextReturnsNoPRExitCode(t *testing.T) {
 	t.Parallel()
 
@@ -147,6 +498,191 @@ func TestExecuteContextReturnsNoPRExitCode(t *testing.T) {
 	}
 }
 
+func TestExecuteContextReturnsPartialFailureExitCode(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	var stderr bytes.Buffer
+
+	exitCode := ExecuteContext(context.Background(), CommandOptions{
+		Args:    []string{"--token", "dummy", "--stages", "develop:staging,staging:main"},
+		WorkDir: workDir,
+		Stderr:  &stderr,
+		LookupEnv: func(string) (string, bool) {
+			return "", false
+		},
+		NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+			return stubService{err: fmt.Errorf("%w: stage staging->main: boom", release.ErrPartialFailure)}
+		},
+	})
+
+	if exitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d", exitCode)
+	}
+	if !strings.Contains(stderr.String(), "boom") {
+		t.Fatalf("stderr does not contain stage error: %q", stderr.String())
+	}
+}
+
+func TestExecuteContextReturnsNotReadyExitCode(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	exitCode := ExecuteContext(context.Background(), CommandOptions{
+		Args:    []string{"--token", "dummy", "--check"},
+		WorkDir: workDir,
+		LookupEnv: func(string) (string, bool) {
+			return "", false
+		},
+		NewService: func(config release.Config, stdout io.Writer, stderr io.Writer) serviceRunner {
+			if !config.Check {
+				t.Errorf("expected check mode")
+			}
+			return stubService{err: release.ErrReleaseNotReady}
+		},
+	})
+
+	if exitCode != 4 {
+		t.Fatalf("expected exit code 4, got %d", exitCode)
+	}
+}
+
+func TestResolveConfigReadsFinalizeSettings(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.finalize.merge-method", "squash")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.finalize.auto-merge", "true")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":    "token",
+		"GIT_PR_RELEASE_FINALIZE": "true",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !config.Finalize || !config.AutoMerge || config.MergeMethod != release.MergeMethodSquash {
+		t.Fatalf("unexpected finalize settings: finalize=%v auto-merge=%v method=%q", config.Finalize, config.AutoMerge, config.MergeMethod)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{mergeMethod: stringOption{value: "fast-forward", set: true}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported merge method") {
+		t.Fatalf("expected merge method error, got %v", err)
+	}
+}
+
+func TestResolveConfigReadsColor(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	tests := []struct {
+		name string
+		env  map[string]string
+		args parsedArgs
+		want string
+	}{
+		{name: "default", env: map[string]string{}, want: release.ColorAuto},
+		{name: "no color", env: map[string]string{"NO_COLOR": "1"}, want: release.ColorNever},
+		{name: "flag wins over no color", env: map[string]string{"NO_COLOR": "1"}, args: parsedArgs{color: stringOption{value: "always", set: true}}, want: release.ColorAlways},
+		{name: "environment", env: map[string]string{"GIT_PR_RELEASE_COLOR": "never"}, want: release.ColorNever},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.env["GIT_PR_RELEASE_TOKEN"] = "token"
+			config, err := resolveConfig(context.Background(), workDir, lookupFromMap(tt.env), tt.args)
+			if err != nil {
+				t.Fatalf("resolve config: %v", err)
+			}
+			if config.Color != tt.want {
+				t.Fatalf("expected color %q, got %q", tt.want, config.Color)
+			}
+		})
+	}
+
+	_, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{color: stringOption{value: "rainbow", set: true}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported color") {
+		t.Fatalf("expected color error, got %v", err)
+	}
+}
+
+func TestResolveConfigDetectsGitHubActions(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	env := map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+		"GITHUB_OUTPUT":        "/tmp/output",
+		"GITHUB_STEP_SUMMARY":  "/tmp/summary",
+	}
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(env), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if config.Actions != (release.ActionsEnvironment{}) {
+		t.Fatalf("expected no actions environment outside GitHub Actions, got %+v", config.Actions)
+	}
+
+	env["GITHUB_ACTIONS"] = "true"
+	config, err = resolveConfig(context.Background(), workDir, lookupFromMap(env), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	want := release.ActionsEnvironment{Enabled: true, OutputPath: "/tmp/output", StepSummaryPath: "/tmp/summary"}
+	if config.Actions != want {
+		t.Fatalf("expected %+v, got %+v", want, config.Actions)
+	}
+}
+
+func TestResolveConfigReadsStages(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stages", "develop:staging,production")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stage.production.from", "staging")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stage.production.to", "main")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stage.production.template", ".github/production.tmpl")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stage.production.labels", "release,production")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.stage.production.paths.include", "services/api,proto/**/*.proto")
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+
+	want := []release.Stage{
+		{StagingBranch: "develop", ProductionBranch: "staging"},
+		{
+			Name:             "production",
+			StagingBranch:    "staging",
+			ProductionBranch: "main",
+			TemplatePath:     ".github/production.tmpl",
+			Labels:           []string{"release", "production"},
+			IncludePaths:     []string{"services/api", "proto/**/*.proto"},
+		},
+	}
+	if !reflect.DeepEqual(config.Stages, want) {
+		t.Fatalf("unexpected stages: %#v", config.Stages)
+	}
+
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":  "token",
+		"GIT_PR_RELEASE_STAGES": "qa",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), `stage "qa" requires`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
 type stubService struct {
 	err error
 }
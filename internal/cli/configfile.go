@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFilePath is relative to the repository root. Its keys mirror the
+// pr-release.* git config keys, which it takes precedence over.
+const configFilePath = ".github/go-pr-release.yml"
+
+type configFileKind int
+
+const (
+	configFileString configFileKind = iota
+	configFileBool
+	configFileInt
+	configFileList
+	configFileSection
+	configFileItems
+	configFileProfiles
+)
+
+// configFileSchema lists every key the file accepts. Keep
+// schema/go-pr-release.schema.json in sync with it.
+var configFileSchema = map[string]configFileKind{
+	"app-id":                   configFileInt,
+	"app-private-key":          configFileString,
+	"app-installation-id":      configFileInt,
+	"github-api":               configFileString,
+	"branch":                   configFileSection,
+	"branch.production":        configFileString,
+	"branch.staging":           configFileString,
+	"template":                 configFileString,
+	"mention":                  configFileString,
+	"labels":                   configFileList,
+	"assign-pr-author":         configFileBool,
+	"request-pr-author-review": configFileBool,
+	"ssl-no-verify":            configFileBool,
+	"categories":               configFileItems,
+	"categories[].title":       configFileString,
+	"categories[].labels":      configFileList,
+	"exclude-labels":           configFileList,
+	"paths":                    configFileSection,
+	"paths.include":            configFileList,
+	"paths.exclude":            configFileList,
+	"stages":                   configFileItems,
+	"stages[].name":            configFileString,
+	"stages[].from":            configFileString,
+	"stages[].to":              configFileString,
+	"stages[].template":        configFileString,
+	"stages[].title":           configFileString,
+	"stages[].labels":          configFileList,
+	"stages[].reviewers":       configFileList,
+	"stages[].paths":           configFileSection,
+	"stages[].paths.include":   configFileList,
+	"stages[].paths.exclude":   configFileList,
+	"rebased":                  configFileBool,
+	"squash-detection":         configFileString,
+	"pr-lookup":                configFileString,
+	"publish":                  configFileSection,
+	"publish.template":         configFileString,
+	"publish.tag":              configFileString,
+	"publish.draft":            configFileBool,
+	"publish.prerelease":       configFileBool,
+	"changelog":                configFileSection,
+	"changelog.path":           configFileString,
+	"changelog.template":       configFileString,
+	"changelog.commit":         configFileBool,
+	"finalize":                 configFileSection,
+	"finalize.merge-method":    configFileString,
+	"finalize.auto-merge":      configFileBool,
+	"cache":                    configFileSection,
+	"cache.dir":                configFileString,
+	"cache.max-size":           configFileString,
+	"retry":                    configFileSection,
+	"retry.max-retries":        configFileInt,
+	"retry.base-delay":         configFileString,
+	"retry.max-delay":          configFileString,
+	"color":                    configFileString,
+	"profiles":                 configFileProfiles,
+}
+
+type configFileValue struct {
+	value string
+	line  int
+}
+
+// configFile holds the file flattened to git config keys. Lists are joined
+// with commas, categories become "Title:label1,label2;..." and stages become
+// stage.<name>.* keys, as they are written in git config.
+type configFile struct {
+	path     string
+	values   map[string]configFileValue
+	profiles map[string]map[string]configFileValue
+}
+
+// loadConfigFile returns nil when the repository has no configuration file.
+func loadConfigFile(root string) (*configFile, error) {
+	data, err := os.ReadFile(filepath.Join(root, configFilePath))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", configFilePath, err)
+	}
+	return parseConfigFile(configFilePath, data)
+}
+
+func parseConfigFile(path string, data []byte) (*configFile, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	file := &configFile{path: path, values: map[string]configFileValue{}, profiles: map[string]map[string]configFileValue{}}
+	if len(document.Content) == 0 {
+		return file, nil
+	}
+	if err := file.readSection(document.Content[0], "", "", file.values); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (f *configFile) lookup(key string) (configFileValue, bool) {
+	if f == nil {
+		return configFileValue{}, false
+	}
+	value, ok := f.values[key]
+	return value, ok
+}
+
+// lookupProfile reads key from the profile name. The inherit key names the
+// profile it inherits from.
+func (f *configFile) lookupProfile(name, key string) (configFileValue, bool) {
+	if f == nil {
+		return configFileValue{}, false
+	}
+	value, ok := f.profiles[name][key]
+	return value, ok
+}
+
+func (f *configFile) hasProfile(name string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.profiles[name]
+	return ok
+}
+
+func (f *configFile) errorf(node *yaml.Node, format string, args ...any) error {
+	return fmt.Errorf("%s:%d: %s", f.path, node.Line, fmt.Sprintf(format, args...))
+}
+
+// readSection validates a mapping against configFileSchema under
+// schemaPrefix and stores its values under keyPrefix.
+func (f *configFile) readSection(node *yaml.Node, schemaPrefix, keyPrefix string, values map[string]configFileValue) error {
+	if node.Kind != yaml.MappingNode {
+		if schemaPrefix == "" {
+			return f.errorf(node, "the configuration must be a mapping")
+		}
+		return f.errorf(node, "%q must be a mapping", strings.TrimSuffix(schemaPrefix, "."))
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		name := schemaPrefix + keyNode.Value
+		kind, ok := configFileSchema[name]
+		if !ok {
+			return f.errorf(keyNode, "unknown key %q", name)
+		}
+		if seen[keyNode.Value] {
+			return f.errorf(keyNode, "duplicate key %q", name)
+		}
+		seen[keyNode.Value] = true
+		if valueNode.Tag == "!!null" {
+			continue
+		}
+
+		key := keyPrefix + keyNode.Value
+		switch kind {
+		case configFileSection:
+			if err := f.readSection(valueNode, name+".", key+".", values); err != nil {
+				return err
+			}
+		case configFileList:
+			if valueNode.Kind != yaml.SequenceNode {
+				return f.errorf(valueNode, "%q must be a list", name)
+			}
+			items := make([]string, 0, len(valueNode.Content))
+			for _, item := range valueNode.Content {
+				if item.Kind != yaml.ScalarNode {
+					return f.errorf(item, "%q must be a list of strings", name)
+				}
+				items = append(items, item.Value)
+			}
+			values[key] = configFileValue{value: strings.Join(items, ","), line: valueNode.Line}
+		case configFileItems:
+			if valueNode.Kind != yaml.SequenceNode {
+				return f.errorf(valueNode, "%q must be a list", name)
+			}
+			var err error
+			switch name {
+			case "categories":
+				err = f.readCategories(valueNode, values)
+			case "stages":
+				err = f.readStages(valueNode, values)
+			}
+			if err != nil {
+				return err
+			}
+		case configFileProfiles:
+			if err := f.readProfiles(valueNode); err != nil {
+				return err
+			}
+		default:
+			if err := f.checkScalar(valueNode, name, kind); err != nil {
+				return err
+			}
+			values[key] = configFileValue{value: valueNode.Value, line: valueNode.Line}
+		}
+	}
+	return nil
+}
+
+func (f *configFile) checkScalar(node *yaml.Node, name string, kind configFileKind) error {
+	switch {
+	case node.Kind != yaml.ScalarNode:
+		return f.errorf(node, "%q must be a single value", name)
+	case kind == configFileBool && node.Tag != "!!bool":
+		return f.errorf(node, "%q must be true or false", name)
+	case kind == configFileInt && node.Tag != "!!int":
+		return f.errorf(node, "%q must be an integer", name)
+	}
+	return nil
+}
+
+func (f *configFile) readCategories(node *yaml.Node, values map[string]configFileValue) error {
+	specs := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		category := map[string]configFileValue{}
+		if err := f.readSection(item, "categories[].", "", category); err != nil {
+			return err
+		}
+		if category["title"].value == "" || category["labels"].value == "" {
+			return f.errorf(item, "categories need a title and labels")
+		}
+		if strings.ContainsAny(category["title"].value, ":;") {
+			return f.errorf(item, "category title %q must not contain ':' or ';'", category["title"].value)
+		}
+		specs = append(specs, category["title"].value+":"+category["labels"].value)
+	}
+	values["categories"] = configFileValue{value: strings.Join(specs, ";"), line: node.Line}
+	return nil
+}
+
+func (f *configFile) readStages(node *yaml.Node, values map[string]configFileValue) error {
+	names := make([]string, 0, len(node.Content))
+	for _, item := range node.Content {
+		stage := map[string]configFileValue{}
+		if err := f.readSection(item, "stages[].", "", stage); err != nil {
+			return err
+		}
+		name := stage["name"].value
+		if name == "" {
+			return f.errorf(item, "stages need a name")
+		}
+		if strings.ContainsAny(name, ",:") {
+			return f.errorf(item, "stage name %q must not contain ',' or ':'", name)
+		}
+		if slices.Contains(names, name) {
+			return f.errorf(item, "duplicate stage %q", name)
+		}
+		delete(stage, "name")
+		for key, value := range stage {
+			values["stage."+name+"."+key] = value
+		}
+		names = append(names, name)
+	}
+	values["stages"] = configFileValue{value: strings.Join(names, ","), line: node.Line}
+	return nil
+}
+
+// readProfiles reads each profile with the top-level keys and an inherit key.
+func (f *configFile) readProfiles(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return f.errorf(node, "%q must be a mapping", "profiles")
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		nameNode, profileNode := node.Content[i], node.Content[i+1]
+		name := nameNode.Value
+		if err := checkProfileName(name); err != nil {
+			return f.errorf(nameNode, "%v", err)
+		}
+		if _, ok := f.profiles[name]; ok {
+			return f.errorf(nameNode, "duplicate profile %q", name)
+		}
+		if profileNode.Kind != yaml.MappingNode {
+			return f.errorf(profileNode, "profile %q must be a mapping", name)
+		}
+
+		values := map[string]configFileValue{}
+		settings := *profileNode
+		settings.Content = nil
+		for j := 0; j+1 < len(profileNode.Content); j += 2 {
+			keyNode, valueNode := profileNode.Content[j], profileNode.Content[j+1]
+			switch keyNode.Value {
+			case "profiles":
+				return f.errorf(keyNode, "profiles cannot be nested")
+			case "inherit":
+				if err := f.checkScalar(valueNode, "inherit", configFileString); err != nil {
+					return err
+				}
+				values["inherit"] = configFileValue{value: valueNode.Value, line: valueNode.Line}
+			default:
+				settings.Content = append(settings.Content, keyNode, valueNode)
+			}
+		}
+		if err := f.readSection(&settings, "", "", values); err != nil {
+			return err
+		}
+		f.profiles[name] = values
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tomtwinkle/go-pr-release/internal/release"
+)
+
+func TestParseConfigFileFlattensToGitConfigKeys(t *testing.T) {
+	t.Parallel()
+
+	file, err := parseConfigFile(configFilePath, []byte(`# yaml-language-server: $schema=../schema/go-pr-release.schema.json
+branch:
+  production: main
+labels: [release, qa]
+assign-pr-author: true
+template:
+categories:
+  - title: Features
+    labels: [feature, enhancement]
+  - title: Bug fixes
+    labels: [bug]
+stages:
+  - name: staging
+    from: develop
+    to: staging
+  - name: production
+    from: staging
+    to: main
+    paths:
+      include: [services/api/**]
+retry:
+  max-retries: 5
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	got := map[string]string{}
+	for key, value := range file.values {
+		got[key] = value.value
+	}
+	want := map[string]string{
+		"branch.production":              "main",
+		"labels":                         "release,qa",
+		"assign-pr-author":               "true",
+		"categories":                     "Features:feature,enhancement;Bug fixes:bug",
+		"stages":                         "staging,production",
+		"stage.staging.from":             "develop",
+		"stage.staging.to":               "staging",
+		"stage.production.from":          "staging",
+		"stage.production.to":            "main",
+		"stage.production.paths.include": "services/api/**",
+		"retry.max-retries":              "5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if line := file.values["labels"].line; line != 4 {
+		t.Fatalf("expected labels on line 4, got %d", line)
+	}
+}
+
+func TestParseConfigFileRejectsInvalidFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown key", content: "labels: [a]\nlabel: b\n", want: `.github/go-pr-release.yml:2: unknown key "label"`},
+		{name: "unknown nested key", content: "branch:\n  develop: dev\n", want: `.github/go-pr-release.yml:2: unknown key "branch.develop"`},
+		{name: "unknown stage key", content: "stages:\n  - name: a\n    from: x\n    branch: y\n", want: `.github/go-pr-release.yml:4: unknown key "stages[].branch"`},
+		{name: "duplicate key", content: "color: auto\ncolor: never\n", want: `.github/go-pr-release.yml:2: duplicate key "color"`},
+		{name: "wrong type", content: "rebased: yes\n", want: `.github/go-pr-release.yml:1: "rebased" must be true or false`},
+		{name: "scalar list", content: "labels: release\n", want: `.github/go-pr-release.yml:1: "labels" must be a list`},
+		{name: "section scalar", content: "publish: true\n", want: `.github/go-pr-release.yml:1: "publish" must be a mapping`},
+		{name: "stage without name", content: "stages:\n  - from: a\n    to: b\n", want: `.github/go-pr-release.yml:2: stages need a name`},
+		{name: "not a mapping", content: "- a\n", want: `.github/go-pr-release.yml:1: the configuration must be a mapping`},
+		{name: "reserved profile", content: "profiles:\n  branch:\n    labels: [a]\n", want: `.github/go-pr-release.yml:2: profile name "branch" is reserved`},
+		{name: "nested profiles", content: "profiles:\n  a:\n    profiles: {}\n", want: `.github/go-pr-release.yml:3: profiles cannot be nested`},
+		{name: "unknown profile key", content: "profiles:\n  a:\n    extends: b\n", want: `.github/go-pr-release.yml:3: unknown key "extends"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseConfigFile(configFilePath, []byte(tt.content))
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfigFileSchemaMatchesJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "schema", "go-pr-release.schema.json"))
+	if err != nil {
+		t.Fatalf("read schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("decode schema: %v", err)
+	}
+	definitions, _ := schema["$defs"].(map[string]any)
+
+	var keys []string
+	var walk func(prefix string, node map[string]any)
+	walk = func(prefix string, node map[string]any) {
+		if ref, ok := node["$ref"].(string); ok {
+			walk(prefix, definitions[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any))
+		}
+		if items, ok := node["items"].(map[string]any); ok && node["type"] == "array" {
+			if _, ok := items["properties"]; ok {
+				walk(prefix+"[]", items)
+			}
+			return
+		}
+		properties, _ := node["properties"].(map[string]any)
+		for name, property := range properties {
+			key := name
+			if prefix != "" {
+				key = strings.TrimPrefix(prefix, ".") + "." + name
+			}
+			keys = append(keys, key)
+			walk(key, property.(map[string]any))
+		}
+	}
+	walk("", schema)
+
+	var want []string
+	for key := range configFileSchema {
+		want = append(want, key)
+	}
+	slices.Sort(keys)
+	slices.Sort(want)
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("schema keys %v do not match %v", keys, want)
+	}
+}
+
+func TestResolveConfigReadsConfigFileBetweenEnvironmentAndGitConfig(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	runGit(t, workDir, "config", "-f", filepath.Join(workDir, ".git-pr-release"), "pr-release.labels", "from-git")
+	runGit(t, workDir, "config", "-f", filepath.Join(workDir, ".git-pr-release"), "pr-release.mention", "author")
+	writeConfigFile(t, workDir, `labels: [from-file]
+branch:
+  staging: develop
+  production: main
+stages:
+  - name: production
+    from: develop
+    to: main
+    labels: [production]
+`)
+
+	config, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN":             "token",
+		"GIT_PR_RELEASE_BRANCH_PRODUCTION": "release",
+	}), parsedArgs{})
+	if err != nil {
+		t.Fatalf("resolve config: %v", err)
+	}
+	if !reflect.DeepEqual(config.Labels, []string{"from-file"}) {
+		t.Fatalf("expected the file to win over git config, got %v", config.Labels)
+	}
+	if config.ProductionBranch != "release" || config.StagingBranch != "develop" {
+		t.Fatalf("unexpected branches: production=%q staging=%q", config.ProductionBranch, config.StagingBranch)
+	}
+	if config.Mention != "author" {
+		t.Fatalf("expected git config to fill keys the file leaves out, got %q", config.Mention)
+	}
+	wantStages := []release.Stage{{Name: "production", StagingBranch: "develop", ProductionBranch: "main", Labels: []string{"production"}}}
+	if !reflect.DeepEqual(config.Stages, wantStages) {
+		t.Fatalf("unexpected stages: %+v", config.Stages)
+	}
+
+	writeConfigFile(t, workDir, "labels: [a]\nreviewers: [b]\n")
+	_, err = resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+		"GIT_PR_RELEASE_TOKEN": "token",
+	}), parsedArgs{})
+	if err == nil || !strings.Contains(err.Error(), `.github/go-pr-release.yml:2: unknown key "reviewers"`) {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestExecuteContextVerbosePrintsConfigSources(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	runGit(t, workDir, "config", "-f", filepath.Join(workDir, ".git-pr-release"), "pr-release.mention", "author")
+	writeConfigFile(t, workDir, "color: never\nlabels: [release]\n")
+
+	var stderr bytes.Buffer
+	exitCode := ExecuteContext(context.Background(), CommandOptions{
+		Args:    []string{"--verbose", "--token", "secret", "--label", "qa"},
+		WorkDir: workDir,
+		Stderr:  &stderr,
+		LookupEnv: lookupFromMap(map[string]string{
+			"GIT_PR_RELEASE_TEMPLATE": ".github/release.tmpl",
+		}),
+		NewService: func(release.Config, io.Writer, io.Writer) serviceRunner {
+			return stubService{}
+		},
+	})
+	if exitCode != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+
+	for _, want := range []string{
+		"token=*** (flag)\n",
+		"labels=qa (flag)\n",
+		"template=.github/release.tmpl (env GIT_PR_RELEASE_TEMPLATE)\n",
+		"color=never (.github/go-pr-release.yml:1)\n",
+		"mention=author (git config pr-release.mention)\n",
+		"branch.production=master (default)\n",
+	} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Fatalf("expected %q in verbose output:\n%s", want, stderr.String())
+		}
+	}
+	if strings.Contains(stderr.String(), "secret") {
+		t.Fatalf("verbose output must not print the token:\n%s", stderr.String())
+	}
+}
+
+func writeConfigFile(t *testing.T, workDir, content string) {
+	t.Helper()
+
+	path := filepath.Join(workDir, configFilePath)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create config dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config file: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// checkProfileName rejects names that would collide with the keys of the base
+// configuration, since both live under pr-release.* in git config.
+func checkProfileName(name string) error {
+	if name == "" {
+		return errors.New("profile name is empty")
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("profile name %q must not contain '.'", name)
+	}
+	if name == "stage" {
+		return fmt.Errorf("profile name %q is reserved", name)
+	}
+	for key := range configFileSchema {
+		if key == name || strings.HasPrefix(key, name+".") || strings.HasPrefix(key, name+"[]") {
+			return fmt.Errorf("profile name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// profileChain returns name followed by the profiles it inherits from, most
+// specific first. projectKeys are the keys set in .git-pr-release.
+func (r *configResolver) profileChain(name string, projectKeys []string) ([]string, error) {
+	var chain []string
+	for name != "" {
+		if err := checkProfileName(name); err != nil {
+			return nil, err
+		}
+		if slices.Contains(chain, name) {
+			return nil, fmt.Errorf("profile inheritance cycle: %s", strings.Join(append(chain, name), " -> "))
+		}
+		defined := r.file.hasProfile(name) || slices.ContainsFunc(projectKeys, func(key string) bool {
+			return strings.HasPrefix(key, "pr-release."+name+".")
+		})
+		if !defined {
+			return nil, fmt.Errorf("profile %q is not defined in %s or .git-pr-release", name, configFilePath)
+		}
+		chain = append(chain, name)
+
+		inherit, ok := r.file.lookupProfile(name, "inherit")
+		if ok {
+			name = inherit.value
+			continue
+		}
+		value, _, err := r.lookupGit(name + ".inherit")
+		if err != nil {
+			return nil, err
+		}
+		name = value
+	}
+	return chain, nil
+}
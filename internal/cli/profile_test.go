@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tomtwinkle/go-pr-release/internal/release"
+)
+
+func TestResolveConfigAppliesProfileChain(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.labels", "base")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.mention", "author")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.weekly.labels", "weekly")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.weekly.branch.production", "main")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.hotfix.inherit", "weekly")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.hotfix.branch.staging", "hotfix")
+	writeConfigFile(t, workDir, `branch:
+  staging: develop
+profiles:
+  mobile:
+    inherit: weekly
+    labels: [mobile]
+`)
+
+	tests := []struct {
+		name           string
+		env            map[string]string
+		args           parsedArgs
+		wantLabels     []string
+		wantProduction string
+		wantStaging    string
+	}{
+		{
+			name:           "no profile",
+			wantLabels:     []string{"base"},
+			wantProduction: "master",
+			wantStaging:    "develop",
+		},
+		{
+			name:           "inherited git profile",
+			env:            map[string]string{"GIT_PR_RELEASE_PROFILE": "hotfix"},
+			wantLabels:     []string{"weekly"},
+			wantProduction: "main",
+			wantStaging:    "hotfix",
+		},
+		{
+			name:           "file profile inheriting a git profile",
+			args:           parsedArgs{profile: stringOption{value: "mobile", set: true}},
+			wantLabels:     []string{"mobile"},
+			wantProduction: "main",
+			wantStaging:    "develop",
+		},
+		{
+			name:           "environment wins over the profile",
+			env:            map[string]string{"GIT_PR_RELEASE_PROFILE": "hotfix", "GIT_PR_RELEASE_LABELS": "env"},
+			wantLabels:     []string{"env"},
+			wantProduction: "main",
+			wantStaging:    "hotfix",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			env := map[string]string{"GIT_PR_RELEASE_TOKEN": "token"}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			config, err := resolveConfig(context.Background(), workDir, lookupFromMap(env), tt.args)
+			if err != nil {
+				t.Fatalf("resolve config: %v", err)
+			}
+			if !reflect.DeepEqual(config.Labels, tt.wantLabels) {
+				t.Fatalf("expected labels %v, got %v", tt.wantLabels, config.Labels)
+			}
+			if config.ProductionBranch != tt.wantProduction || config.StagingBranch != tt.wantStaging {
+				t.Fatalf("unexpected branches: production=%q staging=%q", config.ProductionBranch, config.StagingBranch)
+			}
+			if config.Mention != "author" {
+				t.Fatalf("expected the base configuration to fill the rest, got mention %q", config.Mention)
+			}
+		})
+	}
+}
+
+func TestResolveConfigRejectsInvalidProfiles(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.a.inherit", "b")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.b.inherit", "a")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.c.inherit", "missing")
+
+	tests := []struct {
+		profile string
+		want    string
+	}{
+		{profile: "typo", want: `profile "typo" is not defined`},
+		{profile: "a", want: "profile inheritance cycle: a -> b -> a"},
+		{profile: "c", want: `profile "missing" is not defined`},
+		{profile: "branch", want: `profile name "branch" is reserved`},
+		{profile: "stage", want: `profile name "stage" is reserved`},
+		{profile: "a.b", want: `profile name "a.b" must not contain '.'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := resolveConfig(context.Background(), workDir, lookupFromMap(map[string]string{
+				"GIT_PR_RELEASE_TOKEN":   "token",
+				"GIT_PR_RELEASE_PROFILE": tt.profile,
+			}), parsedArgs{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestExecuteContextVerbosePrintsProfileChain(t *testing.T) {
+	t.Parallel()
+
+	workDir := initGitRepository(t, "git@github.com:octo/example.git")
+	writeConfigFile(t, workDir, `profiles:
+  weekly:
+    labels: [weekly]
+  hotfix:
+    inherit: weekly
+`)
+
+	var stderr bytes.Buffer
+	exitCode := ExecuteContext(context.Background(), CommandOptions{
+		Args:      []string{"--verbose", "--token", "dummy", "--profile", "hotfix"},
+		WorkDir:   workDir,
+		Stderr:    &stderr,
+		LookupEnv: lookupFromMap(nil),
+		NewService: func(release.Config, io.Writer, io.Writer) serviceRunner {
+			return stubService{}
+		},
+	})
+	if exitCode != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	for _, want := range []string{"profiles=hotfix -> weekly\n", "labels=weekly (.github/go-pr-release.yml:3)\n"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Fatalf("expected %q in verbose output:\n%s", want, stderr.String())
+		}
+	}
+}
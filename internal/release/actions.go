@@ -0,0 +1,229 @@
+package release
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ActionsEnvironment describes the GitHub Actions runner. When Enabled, runs
+// write step outputs to OutputPath, append a job summary to StepSummaryPath
+// and annotate recoverable problems with workflow commands.
+type ActionsEnvironment struct {
+	Enabled         bool
+	OutputPath      string
+	StepSummaryPath string
+}
+
+// warn reports a problem the run recovered from.
+func (s *Service) warn(message string) {
+	s.say(message)
+	s.annotate("warning", "", message)
+}
+
+// annotate emits a workflow command. It goes to stderr so --json output on
+// stdout stays parseable; the runner reads commands from both.
+func (s *Service) annotate(level, title, message string) {
+	if !s.config.Actions.Enabled {
+		return
+	}
+	properties := ""
+	if title != "" {
+		properties = " title=" + escapeWorkflowProperty(title)
+	}
+	fmt.Fprintf(s.stderr, "::%s%s::%s\n", level, properties, escapeWorkflowData(message))
+}
+
+// reportActions writes the outputs and the job summary for a run. stages
+// is set when the run processed --stages.
+func (s *Service) reportActions(results []stageResult, stages bool) {
+	if !s.config.Actions.Enabled {
+		return
+	}
+	for _, result := range results {
+		if result.Status == stageStatusFailed {
+			s.annotate("error", "Stage "+result.Name, result.Error)
+		}
+	}
+
+	s.writeActions(actionsOutputs(results, stages), actionsSummary(results))
+}
+
+// reportPublishActions writes the outputs and the job summary for publish,
+// adding the tag and the release.
+func (s *Service) reportPublishActions(result publishResult, mode string) {
+	if !s.config.Actions.Enabled {
+		return
+	}
+	stage := stageResult{
+		Name:               "Release " + result.Release.TagName,
+		Status:             mode,
+		ReleasePullRequest: result.ReleasePullRequest,
+		MergedPullRequests: result.MergedPullRequests,
+		SuggestedVersion:   result.SuggestedVersion,
+	}
+	outputs := bytes.NewBuffer(actionsOutputs([]stageResult{stage}, false))
+	writeActionsOutput(outputs, "tag", result.Release.TagName)
+	writeActionsOutput(outputs, "release-url", result.Release.URL)
+	summary := bytes.NewBuffer(actionsSummary([]stageResult{stage}))
+	if result.Release.URL != "" {
+		fmt.Fprintf(summary, "Release: [%s](%s)\n\n", result.Release.TagName, result.Release.URL)
+	}
+	s.writeActions(outputs.Bytes(), summary.Bytes())
+}
+
+// reportChangelogActions reports how the changelog at path was handled.
+func (s *Service) reportChangelogActions(path, mode string, mergedPRs []PullRequest, version *VersionSuggestion) {
+	if !s.config.Actions.Enabled {
+		return
+	}
+	stage := []stageResult{{
+		Name:               "Changelog " + path,
+		Status:             mode,
+		MergedPullRequests: mergedPRs,
+		SuggestedVersion:   version,
+	}}
+	s.writeActions(actionsOutputs(stage, false), actionsSummary(stage))
+}
+
+// reportFinalizeActions exposes the decision of --check and --finalize as
+// mode.
+func (s *Service) reportFinalizeActions(result finalizeResult) {
+	if !s.config.Actions.Enabled {
+		return
+	}
+	stage := []stageResult{{
+		Name:               s.config.stageName(),
+		Status:             result.Decision,
+		ReleasePullRequest: result.ReleasePullRequest,
+	}}
+	summary := bytes.NewBuffer(actionsSummary(stage))
+	if len(result.Reasons) > 0 {
+		for _, reason := range result.Reasons {
+			fmt.Fprintf(summary, "- %s\n", reason)
+		}
+		summary.WriteString("\n")
+	}
+	if lines := result.uncheckedLines(); len(lines) > 0 {
+		fmt.Fprintf(summary, "Unchecked items:\n\n%s\n\n", strings.Join(lines, "\n"))
+	}
+	s.writeActions(actionsOutputs(stage, false), summary.Bytes())
+}
+
+// writeActions appends outputs to GITHUB_OUTPUT and summary to the job
+// summary.
+func (s *Service) writeActions(outputs, summary []byte) {
+	if path := s.config.Actions.OutputPath; path != "" {
+		if err := appendToFile(path, outputs); err != nil {
+			s.warn(fmt.Sprintf("Write GitHub Actions outputs: %v", err))
+		}
+	}
+	if path := s.config.Actions.StepSummaryPath; path != "" {
+		if err := appendToFile(path, summary); err != nil {
+			s.warn(fmt.Sprintf("Write GitHub Actions step summary: %v", err))
+		}
+	}
+}
+
+// actionsOutputs renders the GITHUB_OUTPUT entries. A single run exposes its
+// result directly; --stages runs expose every stage as JSON.
+func actionsOutputs(results []stageResult, stages bool) []byte {
+	var buf bytes.Buffer
+	if stages {
+		data, _ := json.Marshal(results)
+		writeActionsOutput(&buf, "stages", string(data))
+		return buf.Bytes()
+	}
+
+	result := results[0]
+	number, url := "", ""
+	if pr := result.ReleasePullRequest; pr != nil {
+		number, url = strconv.Itoa(pr.Number), pr.URL
+	}
+	numbers := make([]string, 0, len(result.MergedPullRequests))
+	for _, pr := range result.MergedPullRequests {
+		numbers = append(numbers, strconv.Itoa(pr.Number))
+	}
+	version := ""
+	if result.SuggestedVersion != nil {
+		version = result.SuggestedVersion.Next
+	}
+
+	writeActionsOutput(&buf, "pr-number", number)
+	writeActionsOutput(&buf, "pr-url", url)
+	writeActionsOutput(&buf, "mode", result.Status)
+	writeActionsOutput(&buf, "merged-pr-numbers", strings.Join(numbers, ","))
+	writeActionsOutput(&buf, "suggested-version", version)
+	return buf.Bytes()
+}
+
+// writeActionsOutput uses the heredoc form for multiline values.
+// https://docs.github.com/actions/using-workflows/workflow-commands-for-github-actions#multiline-strings
+func writeActionsOutput(buf *bytes.Buffer, name, value string) {
+	if !strings.ContainsAny(value, "\r\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	random := make([]byte, 8)
+	_, _ = rand.Read(random)
+	delimiter := "ghadelimiter_" + hex.EncodeToString(random)
+	fmt.Fprintf(buf, "%s<<%s\n%s\n%s\n", name, delimiter, value, delimiter)
+}
+
+func actionsSummary(results []stageResult) []byte {
+	var buf bytes.Buffer
+	for _, result := range results {
+		fmt.Fprintf(&buf, "### %s: %s\n\n", result.Name, strings.ReplaceAll(result.Status, "_", " "))
+		if result.Error != "" {
+			fmt.Fprintf(&buf, "> %s\n\n", strings.ReplaceAll(result.Error, "\n", "\n> "))
+		}
+		if pr := result.ReleasePullRequest; pr != nil && pr.URL != "" {
+			fmt.Fprintf(&buf, "Release pull request: [#%d](%s)\n\n", pr.Number, pr.URL)
+		}
+		if version := result.SuggestedVersion; version != nil && version.Next != "" {
+			fmt.Fprintf(&buf, "Suggested version: `%s` (%s)\n\n", version.Next, version.Bump)
+		}
+		if len(result.MergedPullRequests) > 0 {
+			buf.WriteString("| Pull request | Author |\n| --- | --- |\n")
+			for _, pr := range result.MergedPullRequests {
+				fmt.Fprintf(&buf, "| #%d %s | @%s |\n", pr.Number, escapeTableCell(pr.Title), pr.User.LoginName)
+			}
+			buf.WriteString("\n")
+		}
+		if result.Preview != nil {
+			buf.WriteString("<details><summary>Preview</summary>\n\n```diff\n")
+			result.Preview.write(&buf, false)
+			buf.WriteString("```\n\n</details>\n\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+func appendToFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func escapeTableCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+func escapeWorkflowData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+func escapeWorkflowProperty(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(value)
+}
@@ -0,0 +1,310 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceRunWritesGitHubActionsOutputs(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add | feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+		createErr: &APIError{Method: "POST", Path: "/repos/octo/example/pulls", StatusCode: 502},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Actions:          ActionsEnvironment{Enabled: true, OutputPath: outputPath, StepSummaryPath: summaryPath},
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("service run: %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := "pr-number=100\npr-url=https://example.com/pulls/100\nmode=created\nmerged-pr-numbers=1\nsuggested-version=v0.0.1\n"
+	if string(output) != want {
+		t.Fatalf("got outputs:\n%s\nwant:\n%s", output, want)
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	for _, want := range []string{
+		"### staging->master: created\n",
+		"Release pull request: [#100](https://example.com/pulls/100)\n",
+		"Suggested version: `v0.0.1` (patch)\n",
+		`| #1 Add \| feature | @alice |`,
+	} {
+		if !strings.Contains(string(summary), want) {
+			t.Fatalf("expected %q in summary:\n%s", want, summary)
+		}
+	}
+
+	if !strings.Contains(stderr.String(), "::warning::Create pull request failed (POST /repos/octo/example/pulls") {
+		t.Fatalf("expected a warning command for the recovered create: %q", stderr.String())
+	}
+}
+
+func TestServiceRunStagesWritesGitHubActionsOutputs(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Stages: []Stage{
+			{Name: "production", StagingBranch: "staging", ProductionBranch: "master"},
+			{StagingBranch: "staging", ProductionBranch: "missing"},
+		},
+		Actions: ActionsEnvironment{Enabled: true, OutputPath: outputPath, StepSummaryPath: summaryPath},
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); !errors.Is(err, ErrPartialFailure) {
+		t.Fatalf("expected partial failure, got %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	value, ok := strings.CutPrefix(strings.TrimSuffix(string(output), "\n"), "stages=")
+	if !ok {
+		t.Fatalf("expected a stages output, got %q", output)
+	}
+	var results []stageResult
+	if err := json.Unmarshal([]byte(value), &results); err != nil {
+		t.Fatalf("decode stages output: %v", err)
+	}
+	if len(results) != 2 || results[0].Status != stageStatusCreated || results[1].Status != stageStatusFailed {
+		t.Fatalf("unexpected stages output: %+v", results)
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	if !strings.Contains(string(summary), "### production: created\n") || !strings.Contains(string(summary), "### staging->missing: failed\n\n> ") {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+	if !strings.Contains(stderr.String(), "::error title=Stage staging->missing::") {
+		t.Fatalf("expected an error command for the failed stage: %q", stderr.String())
+	}
+}
+
+func TestServicePublishWritesGitHubActionsOutputs(t *testing.T) {
+	t.Parallel()
+
+	workDir, mergeSHA := setupMergedReleasePullRequest(t)
+	client := &fakeReleaseClient{
+		fakeGitHubClient: &fakeGitHubClient{
+			pullRequests: map[int]PullRequest{1: {Number: 1, Title: "feat: add search", Merged: true, User: User{LoginName: "alice"}}},
+		},
+		mergedReleasePullRequests: []PullRequest{
+			{Number: 50, URL: "https://example.com/pulls/50", Merged: true, MergeCommitSHA: mergeSHA, Body: "- [x] #1"},
+		},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+
+	service := newPublishService(workDir, client, Config{
+		Actions: ActionsEnvironment{Enabled: true, OutputPath: outputPath, StepSummaryPath: summaryPath},
+	})
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	want := "pr-number=50\npr-url=https://example.com/pulls/50\nmode=created\nmerged-pr-numbers=1\nsuggested-version=v1.1.0\n" +
+		"tag=v1.1.0\nrelease-url=https://example.com/releases/v1.1.0\n"
+	if string(output) != want {
+		t.Fatalf("got outputs:\n%s\nwant:\n%s", output, want)
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	if !strings.Contains(string(summary), "### Release v1.1.0: created\n") || !strings.Contains(string(summary), "Release: [v1.1.0](https://example.com/releases/v1.1.0)\n") {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+}
+
+func TestServiceUpdateChangelogWritesGitHubActionsOutputs(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Changelog:        true,
+		Actions:          ActionsEnvironment{Enabled: true, OutputPath: outputPath},
+	}, NewGit(workDir), fakeGitHub, io.Discard, io.Discard)
+
+	for range 2 {
+		if err := service.Run(context.Background()); err != nil {
+			t.Fatalf("update changelog: %v", err)
+		}
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	for _, want := range []string{"mode=updated\nmerged-pr-numbers=1\n", "mode=unchanged\nmerged-pr-numbers=1\n"} {
+		if !strings.Contains(string(output), want) {
+			t.Fatalf("expected %q in outputs:\n%s", want, output)
+		}
+	}
+}
+
+func TestServiceFinalizeWritesGitHubActionsOutputs(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeMergeClient{
+		fakeGitHubClient: &fakeGitHubClient{
+			pullRequests: map[int]PullRequest{1: {Number: 1, Merged: true, User: User{LoginName: "alice"}}},
+			releasePullRequests: []PullRequest{
+				{Number: 99, Body: "- [ ] #1 @alice", URL: "https://example.com/pulls/99"},
+			},
+		},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+	summaryPath := filepath.Join(t.TempDir(), "summary")
+
+	service := NewServiceWithClients(Config{
+		Repository:       Repository{Owner: "octo", Name: "example"},
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Mention:          "author",
+		Check:            true,
+		Actions:          ActionsEnvironment{Enabled: true, OutputPath: outputPath, StepSummaryPath: summaryPath},
+	}, nil, client, io.Discard, io.Discard)
+	if err := service.Run(context.Background()); !errors.Is(err, ErrReleaseNotReady) {
+		t.Fatalf("expected not ready, got %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "pr-number=99\npr-url=https://example.com/pulls/99\nmode=pending\n") {
+		t.Fatalf("unexpected outputs:\n%s", output)
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("read summary: %v", err)
+	}
+	if !strings.Contains(string(summary), "### staging->master: pending\n") || !strings.Contains(string(summary), "  - [ ] #1 @alice (waiting for alice)\n") {
+		t.Fatalf("unexpected summary:\n%s", summary)
+	}
+}
+
+func TestServiceRunWithoutGitHubActionsWritesNothing(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+		createErr: &APIError{Method: "POST", Path: "/repos/octo/example/pulls", StatusCode: 502},
+	}
+	outputPath := filepath.Join(t.TempDir(), "output")
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Actions:          ActionsEnvironment{OutputPath: outputPath},
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("service run: %v", err)
+	}
+	if _, err := os.Stat(outputPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected no output file, got %v", err)
+	}
+	if strings.Contains(stderr.String(), "::") {
+		t.Fatalf("expected no workflow commands: %q", stderr.String())
+	}
+}
+
+func TestWriteActionsOutput(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	writeActionsOutput(&buf, "single", "value")
+	writeActionsOutput(&buf, "multi", "a\nb")
+
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "single=value" {
+		t.Fatalf("unexpected single line output: %q", lines[0])
+	}
+	name, delimiter, ok := strings.Cut(lines[1], "<<")
+	if !ok || name != "multi" || delimiter == "" {
+		t.Fatalf("expected heredoc output, got %q", lines[1])
+	}
+	if got := strings.Join(lines[2:], "\n"); got != "a\nb\n"+delimiter+"\n" {
+		t.Fatalf("unexpected heredoc body: %q", got)
+	}
+
+	if got := escapeWorkflowProperty("a:b,c%\n"); got != "a%3Ab%2Cc%25%0A" {
+		t.Fatalf("unexpected escaped property: %q", got)
+	}
+}
@@ -0,0 +1,216 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	appJWTLifetime      = 9 * time.Minute
+	appJWTClockSkew     = time.Minute
+	appTokenRefreshSkew = 5 * time.Minute
+)
+
+type tokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+type githubAppTokenSource struct {
+	httpClient     *http.Client
+	baseURL        string
+	repository     Repository
+	appID          int64
+	privateKey     string
+	installationID int64
+	nowFn          func() time.Time
+
+	mu        sync.Mutex
+	signer    *rsa.PrivateKey
+	token     string
+	expiresAt time.Time
+}
+
+func newGitHubAppTokenSource(config Config, httpClient *http.Client) *githubAppTokenSource {
+	return &githubAppTokenSource{
+		httpClient:     httpClient,
+		baseURL:        config.Repository.APIBaseURL(),
+		repository:     config.Repository,
+		appID:          config.AppID,
+		privateKey:     config.AppPrivateKey,
+		installationID: config.AppInstallationID,
+		nowFn:          time.Now,
+	}
+}
+
+func (s *githubAppTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFn()
+	if s.token != "" && now.Add(appTokenRefreshSkew).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	jwt, err := s.signJWT(now)
+	if err != nil {
+		return "", err
+	}
+
+	if s.installationID == 0 {
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		if err := s.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/installation", s.repository.FullName()), jwt, &installation); err != nil {
+			return "", fmt.Errorf("discover github app installation: %w", err)
+		}
+		if installation.ID == 0 {
+			return "", fmt.Errorf("github app %d is not installed on %s", s.appID, s.repository.FullName())
+		}
+		s.installationID = installation.ID
+	}
+
+	var accessToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := s.request(ctx, http.MethodPost, fmt.Sprintf("app/installations/%d/access_tokens", s.installationID), jwt, &accessToken); err != nil {
+		return "", fmt.Errorf("create github app installation token: %w", err)
+	}
+	if accessToken.Token == "" {
+		return "", errors.New("create github app installation token: empty token")
+	}
+
+	s.token = accessToken.Token
+	s.expiresAt = accessToken.ExpiresAt
+	return s.token, nil
+}
+
+func (s *githubAppTokenSource) signJWT(now time.Time) (string, error) {
+	if s.signer == nil {
+		key, err := loadRSAPrivateKey(s.privateKey)
+		if err != nil {
+			return "", err
+		}
+		s.signer = key
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.signer, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("sign github app jwt: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func (s *githubAppTokenSource) request(ctx context.Context, method, path, jwt string, responseBody any) error {
+	endpoint, err := url.Parse(s.baseURL)
+	if err != nil {
+		return fmt.Errorf("parse github base url: %w", err)
+	}
+	endpoint = endpoint.JoinPath(path)
+
+	var body io.Reader
+	if method != http.MethodGet {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return fmt.Errorf("create github request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "go-pr-release")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, endpoint.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return &APIError{
+			Method:     method,
+			Path:       endpoint.Path,
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(payload)),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
+		return fmt.Errorf("decode github response: %w", err)
+	}
+	return nil
+}
+
+func loadRSAPrivateKey(value string) (*rsa.PrivateKey, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, errors.New("github app private key is not configured")
+	}
+
+	payload := []byte(value)
+	if !strings.Contains(value, "-----BEGIN") {
+		content, err := os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("read github app private key: %w", err)
+		}
+		payload = content
+	}
+
+	block, _ := pem.Decode(payload)
+	if block == nil {
+		return nil, errors.New("parse github app private key: no PEM block found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse github app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("parse github app private key: not an RSA key")
+	}
+	return key, nil
+}
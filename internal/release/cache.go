@@ -0,0 +1,216 @@
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultCacheMaxBytes int64 = 100 << 20
+
+const cacheFileSuffix = ".json"
+
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+type httpCache struct {
+	dir      string
+	maxBytes int64
+	scope    string
+	nowFn    func() time.Time
+
+	mu     sync.Mutex
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheEntry struct {
+	URL          string          `json:"url"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+func newHTTPCache(config Config) *httpCache {
+	if config.NoCache || config.CacheDir == "" {
+		return nil
+	}
+
+	maxBytes := config.CacheMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+
+	scope := "token:" + hashString(config.Token)
+	if config.AppID != 0 {
+		scope = fmt.Sprintf("app:%d:%d", config.AppID, config.AppInstallationID)
+	}
+
+	return &httpCache{
+		dir:      config.CacheDir,
+		maxBytes: maxBytes,
+		scope:    scope,
+		nowFn:    time.Now,
+	}
+}
+
+func (c *httpCache) key(rawURL string) string {
+	return hashString(c.scope + "\n" + rawURL)
+}
+
+func (c *httpCache) load(key string) (*cacheEntry, bool) {
+	payload, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *httpCache) applyValidators(req *http.Request, entry *cacheEntry) {
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+func (c *httpCache) hit(key string) {
+	c.hits.Add(1)
+	now := c.nowFn()
+	_ = os.Chtimes(c.path(key), now, now)
+}
+
+func (c *httpCache) store(key string, rawURL string, header http.Header, body []byte) error {
+	c.misses.Add(1)
+
+	entry := cacheEntry{
+		URL:          rawURL,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Body:         json.RawMessage(body),
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return nil
+	}
+	if !json.Valid(body) {
+		return nil
+	}
+
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if int64(len(payload)) > c.maxBytes {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, "."+key+"-*")
+	if err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if _, err := tmp.Write(payload); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+
+	return c.prune()
+}
+
+func (c *httpCache) prune() error {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	type cachedFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	files := make([]cachedFile, 0, len(dirEntries))
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || !strings.HasSuffix(dirEntry.Name(), cacheFileSuffix) {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, cachedFile{
+			path:    filepath.Join(c.dir, dirEntry.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+		total += info.Size()
+	}
+	if total <= c.maxBytes {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, file := range files {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= file.size
+	}
+	return nil
+}
+
+func (c *httpCache) stats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
+
+func (c *httpCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheFileSuffix)
+}
+
+func hashString(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
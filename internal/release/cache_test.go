@@ -0,0 +1,139 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRESTGitHubClientServesNotModifiedFromCache(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var conditionalHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/api/v3/repos/octo/example/pulls/7" {
+			http.NotFound(w, r)
+			return
+		}
+		conditionalHeaders = append(conditionalHeaders, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 7, Title: "cached"})
+	}))
+	t.Cleanup(server.Close)
+
+	cacheDir := t.TempDir()
+	newClient := func() *RESTGitHubClient {
+		client := newTestGitHubClient(server)
+		client.cache = newHTTPCache(Config{Token: "dummy", CacheDir: cacheDir})
+		return client
+	}
+
+	first := newClient()
+	if _, err := first.getPullRequest(context.Background(), 7); err != nil {
+		t.Fatalf("get pull request: %v", err)
+	}
+	if stats := first.CacheStats(); stats != (CacheStats{Misses: 1}) {
+		t.Fatalf("unexpected first run stats: %+v", stats)
+	}
+
+	second := newClient()
+	pr, err := second.getPullRequest(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("get pull request: %v", err)
+	}
+	if pr.Title != "cached" {
+		t.Fatalf("unexpected cached pull request: %+v", pr)
+	}
+	if stats := second.CacheStats(); stats != (CacheStats{Hits: 1}) {
+		t.Fatalf("unexpected second run stats: %+v", stats)
+	}
+	if len(conditionalHeaders) != 2 || conditionalHeaders[0] != "" || conditionalHeaders[1] != `"v1"` {
+		t.Fatalf("unexpected If-None-Match headers: %q", conditionalHeaders)
+	}
+}
+
+func TestHTTPCacheIsScopedByCredentials(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	first := newHTTPCache(Config{Token: "first", CacheDir: dir})
+	second := newHTTPCache(Config{Token: "second", CacheDir: dir})
+
+	if first.key("https://api.github.com/repos/octo/example") == second.key("https://api.github.com/repos/octo/example") {
+		t.Fatalf("expected cache keys to differ between tokens")
+	}
+	if newHTTPCache(Config{Token: "first", CacheDir: dir, NoCache: true}) != nil {
+		t.Fatalf("expected NoCache to disable the cache")
+	}
+}
+
+func TestHTTPCachePrunesLeastRecentlyUsedEntries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	now := time.Date(2026, 5, 7, 12, 0, 0, 0, time.UTC)
+	cache := newHTTPCache(Config{Token: "dummy", CacheDir: dir, CacheMaxBytes: 600})
+	cache.nowFn = func() time.Time { return now }
+
+	body := []byte(`"` + strings.Repeat("x", 200) + `"`)
+	header := http.Header{"Etag": []string{`"v1"`}}
+
+	store := func(name string) string {
+		t.Helper()
+		key := cache.key(name)
+		if err := cache.store(key, name, header, body); err != nil {
+			t.Fatalf("store %s: %v", name, err)
+		}
+		now = now.Add(time.Minute)
+		if err := os.Chtimes(cache.path(key), now, now); err != nil {
+			t.Fatalf("touch %s: %v", name, err)
+		}
+		return key
+	}
+
+	oldest := store("https://example.test/a")
+	recent := store("https://example.test/b")
+	now = now.Add(time.Minute)
+	cache.hit(oldest)
+	newest := store("https://example.test/c")
+
+	for _, key := range []string{oldest, newest} {
+		if _, ok := cache.load(key); !ok {
+			t.Fatalf("expected %s to survive pruning", key)
+		}
+	}
+	if _, ok := cache.load(recent); ok {
+		t.Fatalf("expected least recently used entry to be pruned")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read cache dir: %v", err)
+	}
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatalf("stat %s: %v", filepath.Join(dir, entry.Name()), err)
+		}
+		total += info.Size()
+	}
+	if total > 600 {
+		t.Fatalf("cache exceeds size limit: %d bytes", total)
+	}
+}
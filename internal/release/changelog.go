@@ -0,0 +1,218 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const DefaultChangelogPath = "CHANGELOG.md"
+
+const DefaultChangelogTemplate = `## [{{ .SuggestedVersion }}] - {{ now | date "2006-01-02" }}
+{{- range .Categories }}
+
+### {{ .Title }}
+{{- range .PullRequests }}
+- {{ .Title }} (#{{ .Number }})
+{{- end }}
+{{- end }}
+{{- with .UncategorizedPullRequests }}
+
+### Changed
+{{- range . }}
+- {{ .Title }} (#{{ .Number }})
+{{- end }}
+{{- end }}
+`
+
+const changelogHeader = "# Changelog\n"
+
+// changelogStatusUnchanged is the Actions mode when the changelog already
+// has the section.
+const changelogStatusUnchanged = "unchanged"
+
+// BuildChangelogSection renders one version section. The first line of the
+// template is the section heading.
+func BuildChangelogSection(repoRoot, templatePath string, input TemplateData) (string, error) {
+	heading, body, err := renderTitleAndBody(repoRoot, templatePath, DefaultChangelogTemplate, input)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(heading, "## ") {
+		return "", fmt.Errorf("changelog heading %q must start with \"## \"", heading)
+	}
+	return strings.TrimRight(heading+"\n"+body, "\n") + "\n", nil
+}
+
+var changelogVersionPattern = regexp.MustCompile(`v?\d+\.\d+\.\d+[0-9A-Za-z.+-]*`)
+
+// InsertChangelogSection places section above the newest version, below any
+// [Unreleased] section. Sections with the same heading (ignoring the date),
+// or for a version newer than released, are pending sections from an earlier
+// run and get replaced, so reruns do not duplicate entries. Without a
+// released version only the same heading is replaced, since every other
+// section may already be released. All other lines are kept as is.
+func InsertChangelogSection(changelog, section, released string) string {
+	if strings.TrimSpace(changelog) == "" {
+		return changelogHeader + "\n" + section
+	}
+
+	lines := strings.Split(strings.ReplaceAll(changelog, "\r\n", "\n"), "\n")
+	sectionLines := strings.Split(strings.TrimRight(section, "\n"), "\n")
+	key := changelogSectionKey(sectionLines[0])
+	releasedVersion, hasReleased := parseSemver(released)
+
+	isPending := func(heading string) bool {
+		if changelogSectionKey(heading) == key {
+			return true
+		}
+		version, ok := parseSemver(changelogVersionPattern.FindString(heading))
+		return ok && hasReleased && version.compare(releasedVersion) > 0
+	}
+
+	var kept []string
+	insertAt := -1
+	skipping := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "## ") {
+			skipping = isPending(line)
+			if insertAt < 0 && !isUnreleasedHeading(line) {
+				insertAt = len(kept)
+			}
+		}
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+
+	if insertAt < 0 {
+		for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+			kept = kept[:len(kept)-1]
+		}
+		appended := append(append(kept, ""), sectionLines...)
+		return strings.Join(append(appended, ""), "\n")
+	}
+	inserted := append([]string(nil), kept[:insertAt]...)
+	if insertAt > 0 && strings.TrimSpace(kept[insertAt-1]) != "" {
+		inserted = append(inserted, "")
+	}
+	inserted = append(inserted, sectionLines...)
+	inserted = append(inserted, "")
+	return strings.Join(append(inserted, kept[insertAt:]...), "\n")
+}
+
+// changelogSectionKey drops the " - 2024-01-02" date so a rerun on another
+// day still finds its section.
+func changelogSectionKey(heading string) string {
+	key, _, _ := strings.Cut(strings.TrimSpace(heading), " - ")
+	return strings.TrimSpace(key)
+}
+
+func isUnreleasedHeading(line string) bool {
+	return strings.EqualFold(changelogSectionKey(line), "## [Unreleased]")
+}
+
+// ContentsClient reads and commits single files on a branch. Only the
+// GitHub clients implement it.
+type ContentsClient interface {
+	GetFile(ctx context.Context, path, branch string) (content string, sha string, err error)
+	PutFile(ctx context.Context, path, branch, message, content, sha string) error
+}
+
+func (s *Service) updateChangelog(ctx context.Context) error {
+	filter, err := s.config.pathFilter()
+	if err != nil {
+		return err
+	}
+	mergedPRs, err := s.fetchMergedPullRequests(ctx, filter)
+	if err != nil {
+		return err
+	}
+	path := s.config.ChangelogPath
+	if path == "" {
+		path = DefaultChangelogPath
+	}
+	if len(mergedPRs) == 0 {
+		s.say("No pull requests to be released")
+		s.reportChangelogActions(path, stageStatusNoPullRequests, nil, nil)
+		return ErrNoPullRequestsToRelease
+	}
+
+	version, err := s.suggestVersion(ctx, s.config.remoteBranch(s.config.ProductionBranch), mergedPRs)
+	if err != nil {
+		return err
+	}
+	root, err := s.git.Root(ctx)
+	if err != nil {
+		return err
+	}
+	section, err := BuildChangelogSection(root, s.config.ChangelogTemplatePath, TemplateData{
+		MergedPullRequests: excludeLabeled(mergedPRs, s.config.ExcludeLabels),
+		Mention:            s.config.Mention,
+		Categories:         s.config.Categories,
+		Version:            version,
+	})
+	if err != nil {
+		return err
+	}
+
+	var current, sha string
+	var contents ContentsClient
+	if s.config.ChangelogCommit {
+		var ok bool
+		contents, ok = s.github.(ContentsClient)
+		if !ok {
+			return fmt.Errorf("committing the changelog is not supported for %s", s.config.Repository.ProviderName())
+		}
+		current, sha, err = contents.GetFile(ctx, path, s.config.StagingBranch)
+	} else {
+		current, err = readChangelog(filepath.Join(root, path))
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := InsertChangelogSection(current, section, version.Current)
+	if updated == current {
+		s.say(fmt.Sprintf("%s is up to date", path))
+		s.reportChangelogActions(path, changelogStatusUnchanged, mergedPRs, &version)
+		return nil
+	}
+	if s.config.DryRun {
+		s.say(fmt.Sprintf("Dry-run. Not updating %s", path))
+		s.say(section)
+		s.reportChangelogActions(path, stageStatusDryRun, mergedPRs, &version)
+		return nil
+	}
+
+	if s.config.ChangelogCommit {
+		message := fmt.Sprintf("Update %s for %s", path, version.Next)
+		if err := contents.PutFile(ctx, path, s.config.StagingBranch, message, updated, sha); err != nil {
+			return err
+		}
+		s.say(fmt.Sprintf("Committed %s to %s", path, s.config.StagingBranch))
+		s.reportChangelogActions(path, stageStatusUpdated, mergedPRs, &version)
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(root, path), []byte(updated), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	s.say(fmt.Sprintf("Updated %s", path))
+	s.reportChangelogActions(path, stageStatusUpdated, mergedPRs, &version)
+	return nil
+}
+
+func readChangelog(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
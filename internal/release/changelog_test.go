@@ -0,0 +1,163 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInsertChangelogSection(t *testing.T) {
+	t.Parallel()
+
+	section := "## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n"
+	tests := []struct {
+		name      string
+		changelog string
+		released  string
+		want      string
+	}{
+		{
+			name: "new file",
+			want: "# Changelog\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n",
+		},
+		{
+			name:      "below unreleased",
+			changelog: "# Changelog\n\n## [Unreleased]\n\n## [v1.0.0] - 2026-01-01\n\n- Initial  \n",
+			released:  "v1.0.0",
+			want:      "# Changelog\n\n## [Unreleased]\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n\n## [v1.0.0] - 2026-01-01\n\n- Initial  \n",
+		},
+		{
+			name:      "no version sections",
+			changelog: "# Changelog\n\nAll notable changes.\n\n\n",
+			want:      "# Changelog\n\nAll notable changes.\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n",
+		},
+		{
+			name:      "rerun on another day",
+			changelog: "# Changelog\n\n## [v1.1.0] - 2026-10-15\n\n- Old entry (#1)\n\n## [v1.0.0] - 2026-01-01\n",
+			released:  "v1.0.0",
+			want:      "# Changelog\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n\n## [v1.0.0] - 2026-01-01\n",
+		},
+		{
+			name:      "pending section with a smaller bump",
+			changelog: "# Changelog\n\n## [v1.0.1] - 2026-10-15\n\n- Fix (#2)\n",
+			released:  "v1.0.0",
+			want:      "# Changelog\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n",
+		},
+		{
+			name:      "no released version keeps history",
+			changelog: "# Changelog\n\n## [v1.1.0] - 2026-10-15\n\n- Old entry (#1)\n\n## [v1.0.1] - 2026-02-01\n\n- Fix (#2)\n\n## [v1.0.0] - 2026-01-01\n\n- Initial\n",
+			want:      "# Changelog\n\n## [v1.1.0] - 2026-10-16\n\n### Added\n- Search (#1)\n\n## [v1.0.1] - 2026-02-01\n\n- Fix (#2)\n\n## [v1.0.0] - 2026-01-01\n\n- Initial\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := InsertChangelogSection(tt.changelog, section, tt.released)
+			if got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if again := InsertChangelogSection(got, section, tt.released); again != got {
+				t.Fatalf("rerun changed the changelog:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateChangelogWritesFileOnce(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "tag", "v1.0.0", "origin/master")
+	writeFile(t, filepath.Join(workDir, "CHANGELOG.md"), "# Changelog\n\n## [v1.0.0] - 2026-01-01\n\n- Initial\n")
+
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "feat: add search", Merged: true, Labels: []string{"feature"}},
+		},
+	}
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		NoFetch:          true,
+		Changelog:        true,
+		Categories:       []Category{{Title: "Added", Labels: []string{"feature"}}},
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("update changelog: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(workDir, "CHANGELOG.md"))
+	if err != nil {
+		t.Fatalf("read changelog: %v", err)
+	}
+	if !strings.Contains(string(content), "## [v1.1.0] - ") || !strings.Contains(string(content), "### Added\n- feat: add search (#1)\n\n## [v1.0.0]") {
+		t.Fatalf("unexpected changelog:\n%s", content)
+	}
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("update changelog again: %v", err)
+	}
+	if !strings.Contains(stderr.String(), "CHANGELOG.md is up to date") {
+		t.Fatalf("expected rerun to be a no-op, stderr: %s", stderr.String())
+	}
+}
+
+func TestRESTGitHubClientGetAndPutFile(t *testing.T) {
+	t.Parallel()
+
+	var put map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/octo/example/contents/CHANGELOG.md" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Query().Get("ref") != "staging" {
+				http.NotFound(w, r)
+				return
+			}
+			encoded := base64.StdEncoding.EncodeToString([]byte("# Changelog\n"))
+			_ = json.NewEncoder(w).Encode(contentDTO{SHA: "blob1", Content: encoded[:8] + "\n" + encoded[8:]})
+		case http.MethodPut:
+			_ = json.NewDecoder(r.Body).Decode(&put)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	content, sha, err := client.GetFile(context.Background(), "CHANGELOG.md", "staging")
+	if err != nil {
+		t.Fatalf("get file: %v", err)
+	}
+	if content != "# Changelog\n" || sha != "blob1" {
+		t.Fatalf("unexpected file: %q %q", content, sha)
+	}
+
+	if _, _, err := client.GetFile(context.Background(), "CHANGELOG.md", "missing"); err != nil {
+		t.Fatalf("missing file should not fail: %v", err)
+	}
+
+	if err := client.PutFile(context.Background(), "CHANGELOG.md", "staging", "Update", "# New\n", "blob1"); err != nil {
+		t.Fatalf("put file: %v", err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(put["content"])
+	if put["sha"] != "blob1" || put["branch"] != "staging" || string(decoded) != "# New\n" {
+		t.Fatalf("unexpected put request: %v", put)
+	}
+}
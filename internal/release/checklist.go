@@ -0,0 +1,174 @@
+package release
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	listItemPattern = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+(?:\[([ xX])\](?:\s+|$))?(.*)$`)
+	// pullRequestRefPattern matches a pull request reference at the start of
+	// an item: "#12", "[#12](...)", "[title](.../pull/12)" or a bare URL.
+	// URL references capture the repository URL before the number.
+	pullRequestRefPattern = regexp.MustCompile(`^(?:\[[^\]]*\]\((\S+?)/(?:pull|pulls|merge_requests)/(\d+)\)|\[#?(\d+)\]|#(\d+)\b|<?(https?://\S+?)/(?:pull|pulls|merge_requests)/(\d+)\b)`)
+)
+
+// taskListItem is one markdown list item. Items that reference a pull
+// request are keyed by its number wherever they appear; other items are
+// keyed by their text under their parent so nested sub-items keep their own
+// state.
+type taskListItem struct {
+	line   int
+	key    string
+	text   string
+	number int
+	// owner is the pull request the item belongs to: its own number or that
+	// of the closest pull request item above it.
+	owner int
+	// mark is " ", "x" or "X" for task items and "" for plain list items.
+	mark   string
+	markAt int
+}
+
+func (i taskListItem) task() bool {
+	return i.mark != ""
+}
+
+func (i taskListItem) checked() bool {
+	return i.mark == "x" || i.mark == "X"
+}
+
+// parseTaskList reads the list items of a markdown body, skipping fenced
+// code blocks. Only URLs of repository's own pull requests count as
+// references.
+func parseTaskList(lines []string, repository Repository) []taskListItem {
+	type parent struct {
+		indent int
+		key    string
+		owner  int
+	}
+
+	var items []taskListItem
+	var parents []parent
+	fenced := false
+	for idx, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		match := listItemPattern.FindStringSubmatchIndex(line)
+		if match == nil {
+			if trimmed == "" || indentWidth(line) == 0 {
+				parents = parents[:0]
+			}
+			continue
+		}
+
+		indent := indentWidth(line[match[2]:match[3]])
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+
+		item := taskListItem{line: idx, text: strings.TrimSpace(line[match[6]:match[7]])}
+		if match[4] >= 0 {
+			item.mark = line[match[4]:match[5]]
+			item.markAt = match[4]
+		}
+		if number, ok := pullRequestReference(item.text, repository); ok {
+			item.number = number
+			item.owner = number
+			item.key = "#" + strconv.Itoa(number)
+		} else {
+			parentKey := ""
+			if len(parents) > 0 {
+				parentKey = parents[len(parents)-1].key
+				item.owner = parents[len(parents)-1].owner
+			}
+			item.key = parentKey + "/" + item.text
+		}
+
+		items = append(items, item)
+		parents = append(parents, parent{indent: indent, key: item.key, owner: item.owner})
+	}
+	return items
+}
+
+func pullRequestReference(text string, repository Repository) (int, bool) {
+	match := pullRequestRefPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, false
+	}
+	group := match[3] + match[4]
+	switch {
+	case match[2] != "":
+		if !isRepositoryURL(match[1], repository) {
+			return 0, false
+		}
+		group = match[2]
+	case match[6] != "":
+		if !isRepositoryURL(match[5], repository) {
+			return 0, false
+		}
+		group = match[6]
+	}
+	number, err := strconv.Atoi(group)
+	return number, err == nil
+}
+
+// isRepositoryURL reports whether rawURL is the web URL of repository, so
+// links to other hosts or repositories stay plain text items.
+func isRepositoryURL(rawURL string, repository Repository) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || repository.FullName() == "" {
+		return false
+	}
+	host := repository.Host
+	if host == "" {
+		host = "github.com"
+	}
+	// GitLab puts "/-/" between the project and merge_requests.
+	path := strings.TrimSuffix(strings.Trim(parsed.Path, "/"), "/-")
+	return strings.EqualFold(parsed.Host, host) && strings.EqualFold(path, repository.FullName())
+}
+
+func indentWidth(prefix string) int {
+	width := 0
+	for _, r := range prefix {
+		if r == '\t' {
+			width += 4
+			continue
+		}
+		width++
+	}
+	return width
+}
+
+// checklistStates returns the mark of every task item by key.
+func checklistStates(items []taskListItem) map[string]string {
+	states := map[string]string{}
+	for _, item := range items {
+		if item.task() {
+			states[item.key] = item.mark
+		}
+	}
+	return states
+}
+
+// withMarks returns lines with each task item's mark replaced by mark(item).
+func withMarks(lines []string, items []taskListItem, mark func(taskListItem) string) []string {
+	marked := append([]string(nil), lines...)
+	for _, item := range items {
+		if !item.task() {
+			continue
+		}
+		line := marked[item.line]
+		marked[item.line] = line[:item.markAt] + mark(item) + line[item.markAt+1:]
+	}
+	return marked
+}
@@ -0,0 +1,174 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTaskList(t *testing.T) {
+	t.Parallel()
+
+	lines := splitLines("# Release\n" +
+		"- [ ] #1 One\n" +
+		"  - [x] migrated\n" +
+		"    * [X] verified on staging\n" +
+		"* [x] https://github.com/octo/example/pull/2\n" +
+		"+ [ ] [#3](https://github.com/octo/example/pull/3) Three\n" +
+		"1. [ ] [Four](https://github.com/octo/example/pull/4)\n" +
+		"- [ ] https://github.com/other/example/pull/7\n" +
+		"- [ ] [Eight](https://ghe.example.com/octo/example/pull/8)\n" +
+		"- Notes\n" +
+		"  - [ ] deploy\n" +
+		"```\n" +
+		"- [ ] #5 in a code block\n" +
+		"```\n" +
+		"- [ ] fix #6 later\n")
+
+	type item struct {
+		key    string
+		number int
+		mark   string
+	}
+	var got []item
+	for _, parsed := range parseTaskList(lines, Repository{Owner: "octo", Name: "example"}) {
+		got = append(got, item{key: parsed.key, number: parsed.number, mark: parsed.mark})
+	}
+	want := []item{
+		{key: "#1", number: 1, mark: " "},
+		{key: "#1/migrated", mark: "x"},
+		{key: "#1/migrated/verified on staging", mark: "X"},
+		{key: "#2", number: 2, mark: "x"},
+		{key: "#3", number: 3, mark: " "},
+		{key: "#4", number: 4, mark: " "},
+		{key: "/https://github.com/other/example/pull/7", mark: " "},
+		{key: "/[Eight](https://ghe.example.com/octo/example/pull/8)", mark: " "},
+		{key: "/Notes"},
+		{key: "/Notes/deploy", mark: " "},
+		{key: "/fix #6 later", mark: " "},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected items:\n got: %+v\nwant: %+v", got, want)
+	}
+}
+
+func TestPullRequestReferenceChecksRepository(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		text       string
+		repository Repository
+		want       int
+	}{
+		{name: "github.com", text: "https://github.com/octo/example/pull/1", repository: Repository{Owner: "octo", Name: "example"}, want: 1},
+		{name: "case insensitive", text: "https://GitHub.com/Octo/Example/pull/2", repository: Repository{Owner: "octo", Name: "example"}, want: 2},
+		{name: "enterprise host", text: "<https://ghe.example.com/octo/example/pull/3>", repository: Repository{Host: "ghe.example.com", Owner: "octo", Name: "example"}, want: 3},
+		{name: "gitlab nested group", text: "[Four](https://gitlab.com/group/sub/project/-/merge_requests/4)", repository: Repository{Host: "gitlab.com", Owner: "group/sub", Name: "project", Provider: ProviderGitLab}, want: 4},
+		{name: "other host", text: "https://ghe.example.com/octo/example/pull/5", repository: Repository{Owner: "octo", Name: "example"}},
+		{name: "other repository", text: "[#6](https://github.com/octo/other/pull/6)", repository: Repository{Owner: "octo", Name: "example"}},
+		{name: "other repository link", text: "[Seven](https://github.com/octo/other/pull/7)", repository: Repository{Owner: "octo", Name: "example"}},
+		{name: "relative link", text: "[Eight](../pull/8)", repository: Repository{Owner: "octo", Name: "example"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, _ := pullRequestReference(tt.text, tt.repository)
+			if got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeBodiesKeepsTaskListState(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		oldBody string
+		newBody string
+		want    string
+	}{
+		{
+			name:    "asterisk bullets and uppercase marks",
+			oldBody: "* [X] #1 One\n* [x] #2 Two",
+			newBody: "- [ ] #1 One\n- [ ] #2 Two\n- [ ] #3 Three",
+			want:    "- [X] #1 One\n- [x] #2 Two\n- [ ] #3 Three",
+		},
+		{
+			name:    "full URL references",
+			oldBody: "- [x] https://github.com/octo/example/pull/1\n- [ ] https://github.com/octo/example/pull/2",
+			newBody: "- [ ] #2 Two\n- [ ] #1 One",
+			want:    "- [ ] #2 Two\n- [x] #1 One",
+		},
+		{
+			name:    "nested sub-items follow their pull request",
+			oldBody: "- [ ] #1 One\n  - [x] migrated\n  - [ ] verified\n- [x] #2 Two\n  - [ ] verified",
+			newBody: "- [ ] #2 Two\n  - [ ] verified\n- [ ] #1 One\n  - [ ] migrated\n  - [ ] verified",
+			want:    "- [x] #2 Two\n  - [ ] verified\n- [ ] #1 One\n  - [x] migrated\n  - [ ] verified",
+		},
+		{
+			name:    "indented pull request items",
+			oldBody: "## Features\n  - [x] #1 One",
+			newBody: "## Features\n- [ ] #1 One\n- [ ] #2 Two",
+			want:    "## Features\n- [x] #1 One\n- [ ] #2 Two",
+		},
+		{
+			name:    "code blocks are left alone",
+			oldBody: "- [x] #1 One\n```\n- [ ] #1 example\n```",
+			newBody: "- [ ] #1 One\n```\n- [ ] #1 example\n```",
+			want:    "- [x] #1 One\n```\n- [ ] #1 example\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := MergeBodies(tt.oldBody, tt.newBody, Repository{Owner: "octo", Name: "example"})
+			if got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if again := MergeBodies(got, tt.newBody, Repository{Owner: "octo", Name: "example"}); again != got {
+				t.Fatalf("merging again changed the body:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestServiceRunOverwriteDescriptionDropsChecklistState(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+		releasePullRequests: []PullRequest{
+			{Number: 99, Body: "* [X] #1 @alice\nQA notes"},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:              workDir,
+		RemoteName:           DefaultRemoteName,
+		Repository:           Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:                "dummy",
+		ProductionBranch:     "master",
+		StagingBranch:        "staging",
+		OverwriteDescription: true,
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("service run: %v", err)
+	}
+	if !strings.Contains(fakeGitHub.updatedBody, "- [ ] #1 @alice") || strings.Contains(fakeGitHub.updatedBody, "QA notes") {
+		t.Fatalf("expected a fresh body: %q", fakeGitHub.updatedBody)
+	}
+}
@@ -1,15 +1,26 @@
 package release
 
 type Config struct {
+	Command               string
 	WorkDir               string
 	RemoteName            string
 	Repository            Repository
 	Token                 string
+	AppID                 int64
+	AppPrivateKey         string
+	AppInstallationID     int64
+	GitHubAPI             string
+	GitBackend            string
 	Title                 string
 	ProductionBranch      string
 	StagingBranch         string
 	TemplatePath          string
+	Stages                []Stage
+	IncludePaths          []string
+	ExcludePaths          []string
 	Labels                []string
+	Categories            []Category
+	ExcludeLabels         []string
 	ExtraReviewers        []string
 	Mention               string
 	AssignPRAuthor        bool
@@ -18,7 +29,65 @@ type Config struct {
 	JSON                  bool
 	NoFetch               bool
 	Squashed              bool
+	SquashDetection       string
+	Rebased               bool
+	PullRequestLookup     string
 	OverwriteDescription  bool
+	Publish               bool
+	ReleaseTemplatePath   string
+	TagPattern            string
+	Draft                 bool
+	Prerelease            bool
+	Changelog             bool
+	ChangelogPath         string
+	ChangelogTemplatePath string
+	ChangelogCommit       bool
+	Check                 bool
+	Finalize              bool
+	MergeMethod           string
+	AutoMerge             bool
 	Verbose               bool
+	Color                 string
+	Actions               ActionsEnvironment
+	CacheDir              string
+	CacheMaxBytes         int64
+	NoCache               bool
+	Retry                 RetryPolicy
 	InsecureSkipTLSVerify bool
 }
+
+// Commands other than CommandRun only read; they never change the release
+// pull request.
+const (
+	CommandRun     = "run"
+	CommandPreview = "preview"
+	CommandStatus  = "status"
+	CommandDoctor  = "doctor"
+)
+
+const (
+	GitHubAPIREST    = "rest"
+	GitHubAPIGraphQL = "graphql"
+)
+
+const (
+	SquashDetectionSearch  = "search"
+	SquashDetectionSubject = "subject"
+)
+
+const (
+	MergeMethodMerge  = "merge"
+	MergeMethodSquash = "squash"
+	MergeMethodRebase = "rebase"
+)
+
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+const (
+	PullRequestLookupScan    = "scan"
+	PullRequestLookupCommits = "commits"
+)
@@ -0,0 +1,56 @@
+package release
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GetFile returns the content and blob SHA of path on branch, or empty
+// strings when the file does not exist yet.
+func (c *RESTGitHubClient) GetFile(ctx context.Context, path, branch string) (string, string, error) {
+	query := url.Values{}
+	query.Set("ref", branch)
+
+	var response contentDTO
+	if err := c.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/contents/%s", c.repository.FullName(), path), query, nil, &response); err != nil {
+		if isNotFound(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	content, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(response.Content, "\n", ""))
+	if err != nil {
+		return "", "", fmt.Errorf("decode %s: %w", path, err)
+	}
+	return string(content), response.SHA, nil
+}
+
+func (c *RESTGitHubClient) PutFile(ctx context.Context, path, branch, message, content, sha string) error {
+	request := map[string]string{
+		"message": message,
+		"content": base64.StdEncoding.EncodeToString([]byte(content)),
+		"branch":  branch,
+	}
+	if sha != "" {
+		request["sha"] = sha
+	}
+	return c.request(ctx, http.MethodPut, fmt.Sprintf("repos/%s/contents/%s", c.repository.FullName(), path), nil, request, nil)
+}
+
+func (c *GraphQLGitHubClient) GetFile(ctx context.Context, path, branch string) (string, string, error) {
+	return c.rest.GetFile(ctx, path, branch)
+}
+
+func (c *GraphQLGitHubClient) PutFile(ctx context.Context, path, branch, message, content, sha string) error {
+	return c.rest.PutFile(ctx, path, branch, message, content, sha)
+}
+
+type contentDTO struct {
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
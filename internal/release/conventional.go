@@ -0,0 +1,161 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	VersionBumpMajor = "major"
+	VersionBumpMinor = "minor"
+	VersionBumpPatch = "patch"
+)
+
+// conventionalTypeOther collects pull requests whose titles are not
+// Conventional Commits in PullRequestsByType.
+const conventionalTypeOther = "other"
+
+var (
+	conventionalTitlePattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+	breakingFooterPattern    = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+	semverPattern            = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+)
+
+type ConventionalCommit struct {
+	Type        string `json:"type"`
+	Scope       string `json:"scope,omitempty"`
+	Description string `json:"description"`
+	Breaking    bool   `json:"breaking,omitempty"`
+}
+
+// ParseConventionalCommit parses a pull request title such as
+// "feat(api)!: drop v1 endpoints". A "BREAKING CHANGE:" footer in the body
+// also marks the change as breaking.
+func ParseConventionalCommit(pr PullRequest) (ConventionalCommit, bool) {
+	match := conventionalTitlePattern.FindStringSubmatch(strings.TrimSpace(pr.Title))
+	if match == nil {
+		return ConventionalCommit{}, false
+	}
+	return ConventionalCommit{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[2]),
+		Description: strings.TrimSpace(match[4]),
+		Breaking:    match[3] == "!" || breakingFooterPattern.MatchString(pr.Body),
+	}, true
+}
+
+type VersionSuggestion struct {
+	Current string `json:"current,omitempty"`
+	Next    string `json:"next"`
+	Bump    string `json:"bump"`
+}
+
+// SuggestVersion bumps the latest semver tag by the largest change among the
+// pull requests: breaking changes bump major, feat bumps minor and anything
+// else bumps patch.
+func SuggestVersion(tags []string, pullRequests []PullRequest) VersionSuggestion {
+	bump := VersionBumpPatch
+	for _, pr := range pullRequests {
+		commit, ok := ParseConventionalCommit(pr)
+		if !ok {
+			continue
+		}
+		if commit.Breaking {
+			bump = VersionBumpMajor
+			break
+		}
+		if commit.Type == "feat" {
+			bump = VersionBumpMinor
+		}
+	}
+
+	latest, found := latestSemver(tags)
+	next := latest.bump(bump)
+	suggestion := VersionSuggestion{Next: next.String(), Bump: bump}
+	if found {
+		suggestion.Current = latest.String()
+	}
+	return suggestion
+}
+
+// suggestVersion bases the suggestion on the tags reachable from revision.
+func (s *Service) suggestVersion(ctx context.Context, revision string, pullRequests []PullRequest) (VersionSuggestion, error) {
+	tags, err := s.git.MergedTags(ctx, revision)
+	if err != nil {
+		return VersionSuggestion{}, fmt.Errorf("list tags on %s: %w", revision, err)
+	}
+	return SuggestVersion(tags, pullRequests), nil
+}
+
+type semver struct {
+	prefix              string
+	major, minor, patch int
+	prerelease          string
+}
+
+func parseSemver(tag string) (semver, bool) {
+	match := semverPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return semver{}, false
+	}
+	version := semver{prefix: match[1], prerelease: match[5]}
+	for idx, target := range []*int{&version.major, &version.minor, &version.patch} {
+		value, err := strconv.Atoi(match[idx+2])
+		if err != nil {
+			return semver{}, false
+		}
+		*target = value
+	}
+	return version, true
+}
+
+// latestSemver picks the highest release tag. Pre-release tags are ignored
+// so that a release candidate does not become the base of the next bump.
+func latestSemver(tags []string) (semver, bool) {
+	latest := semver{prefix: "v"}
+	found := false
+	for _, tag := range tags {
+		version, ok := parseSemver(tag)
+		if !ok || version.prerelease != "" {
+			continue
+		}
+		if !found || version.compare(latest) > 0 {
+			latest = version
+			found = true
+		}
+	}
+	return latest, found
+}
+
+func (v semver) compare(other semver) int {
+	for _, diff := range []int{v.major - other.major, v.minor - other.minor, v.patch - other.patch} {
+		if diff != 0 {
+			return diff
+		}
+	}
+	return 0
+}
+
+func (v semver) bump(level string) semver {
+	next := semver{prefix: v.prefix, major: v.major, minor: v.minor, patch: v.patch}
+	switch level {
+	case VersionBumpMajor:
+		next.major, next.minor, next.patch = v.major+1, 0, 0
+	case VersionBumpMinor:
+		next.minor, next.patch = v.minor+1, 0
+	default:
+		next.patch = v.patch + 1
+	}
+	return next
+}
+
+func (v semver) String() string {
+	version := fmt.Sprintf("%s%d.%d.%d", v.prefix, v.major, v.minor, v.patch)
+	if v.prerelease != "" {
+		version += "-" + v.prerelease
+	}
+	return version
+}
@@ -0,0 +1,161 @@
+package release
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		pr    PullRequest
+		want  ConventionalCommit
+		valid bool
+	}{
+		{
+			name:  "type only",
+			pr:    PullRequest{Title: "fix: handle empty body"},
+			want:  ConventionalCommit{Type: "fix", Description: "handle empty body"},
+			valid: true,
+		},
+		{
+			name:  "scope",
+			pr:    PullRequest{Title: "feat(api): add search endpoint"},
+			want:  ConventionalCommit{Type: "feat", Scope: "api", Description: "add search endpoint"},
+			valid: true,
+		},
+		{
+			name:  "breaking marker",
+			pr:    PullRequest{Title: "Feat(api)!: drop v1"},
+			want:  ConventionalCommit{Type: "feat", Scope: "api", Description: "drop v1", Breaking: true},
+			valid: true,
+		},
+		{
+			name:  "breaking footer",
+			pr:    PullRequest{Title: "refactor: rename config keys", Body: "Details\n\nBREAKING CHANGE: keys were renamed"},
+			want:  ConventionalCommit{Type: "refactor", Description: "rename config keys", Breaking: true},
+			valid: true,
+		},
+		{
+			name: "plain title",
+			pr:   PullRequest{Title: "Update README"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ParseConventionalCommit(tt.pr)
+			if ok != tt.valid {
+				t.Fatalf("got valid %v, want %v", ok, tt.valid)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestVersion(t *testing.T) {
+	t.Parallel()
+
+	tags := []string{"v1.2.3", "v1.10.0", "v2.0.0-rc.1", "nightly", "v1.9.9"}
+	tests := []struct {
+		name  string
+		tags  []string
+		title string
+		want  VersionSuggestion
+	}{
+		{name: "patch", tags: tags, title: "fix: typo", want: VersionSuggestion{Current: "v1.10.0", Next: "v1.10.1", Bump: VersionBumpPatch}},
+		{name: "minor", tags: tags, title: "feat: search", want: VersionSuggestion{Current: "v1.10.0", Next: "v1.11.0", Bump: VersionBumpMinor}},
+		{name: "major", tags: tags, title: "fix!: drop v1", want: VersionSuggestion{Current: "v1.10.0", Next: "v2.0.0", Bump: VersionBumpMajor}},
+		{name: "non conventional", tags: tags, title: "Update README", want: VersionSuggestion{Current: "v1.10.0", Next: "v1.10.1", Bump: VersionBumpPatch}},
+		{name: "no tags", title: "feat: first", want: VersionSuggestion{Next: "v0.1.0", Bump: VersionBumpMinor}},
+		{name: "unprefixed tags", tags: []string{"0.3.1"}, title: "fix: bug", want: VersionSuggestion{Current: "0.3.1", Next: "0.3.2", Bump: VersionBumpPatch}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := SuggestVersion(tt.tags, []PullRequest{{Title: "chore: deps"}, {Title: tt.title}})
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTitleAndBodyExposesConventionalCommits(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	templatePath := filepath.Join(root, "template.tmpl")
+	if err := os.WriteFile(templatePath, []byte(`Release {{ .SuggestedVersion }} ({{ .VersionBump }})
+{{- range $type, $prs := .PullRequestsByType }}
+## {{ $type }}
+{{- range $prs }}
+- #{{ .Number }} {{ with .Conventional.Scope }}**{{ . }}:** {{ end }}{{ .Conventional.Description }}
+{{- end }}
+{{- end }}
+`), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	title, body, err := BuildTitleAndBody(root, "template.tmpl", TemplateData{
+		MergedPullRequests: []PullRequest{
+			{Number: 1, Title: "feat(api): add search"},
+			{Number: 2, Title: "fix: handle nil"},
+			{Number: 3, Title: "Update README"},
+		},
+		Version: VersionSuggestion{Current: "v1.0.0", Next: "v1.1.0", Bump: VersionBumpMinor},
+	})
+	if err != nil {
+		t.Fatalf("build title and body: %v", err)
+	}
+
+	if title != "Release v1.1.0 (minor)" {
+		t.Fatalf("unexpected title: %q", title)
+	}
+	want := `## feat
+- #1 **api:** add search
+## fix
+- #2 handle nil
+## other
+- #3 `
+	if got := strings.TrimSpace(body); got != strings.TrimSpace(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMergedTagsMatchesAcrossBackends(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "tag", "v1.0.0", "origin/master")
+	runGit(t, workDir, "tag", "-a", "v1.1.0", "-m", "annotated", "origin/master")
+	runGit(t, workDir, "tag", "v2.0.0-staging", "origin/staging")
+
+	ctx := context.Background()
+	want, err := NewGit(workDir).MergedTags(ctx, "origin/master")
+	if err != nil {
+		t.Fatalf("exec merged tags: %v", err)
+	}
+	got, err := NewGoGit(workDir).MergedTags(ctx, "origin/master")
+	if err != nil {
+		t.Fatalf("go-git merged tags: %v", err)
+	}
+	if !reflect.DeepEqual(want, []string{"v1.0.0", "v1.1.0"}) {
+		t.Fatalf("unexpected exec tags: %v", want)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
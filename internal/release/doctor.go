@@ -0,0 +1,216 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+var ErrDoctorFailed = errors.New("doctor found problems")
+
+// AccessChecker reports what the configured token can do. Only the GitHub
+// clients implement it.
+type AccessChecker interface {
+	CheckAccess(ctx context.Context) (TokenAccess, error)
+}
+
+type TokenAccess struct {
+	// Scopes is nil for tokens that do not report OAuth scopes, such as
+	// fine-grained tokens and GitHub App installation tokens.
+	Scopes []string `json:"scopes"`
+	Push   *bool    `json:"push,omitempty"`
+}
+
+// CheckAccess reads the repository with the token. Classic tokens list their
+// scopes in X-OAuth-Scopes.
+func (c *RESTGitHubClient) CheckAccess(ctx context.Context) (TokenAccess, error) {
+	endpoint, err := url.Parse(c.baseURL)
+	if err != nil {
+		return TokenAccess{}, fmt.Errorf("parse github base url: %w", err)
+	}
+	endpoint = endpoint.JoinPath("repos", c.repository.Owner, c.repository.Name)
+
+	var repository struct {
+		Permissions *struct {
+			Push bool `json:"push"`
+		} `json:"permissions"`
+	}
+	header, err := c.doWithHeaders(ctx, http.MethodGet, endpoint, nil, &repository)
+	if err != nil {
+		return TokenAccess{}, err
+	}
+
+	var access TokenAccess
+	if scopes, ok := header["X-Oauth-Scopes"]; ok {
+		access.Scopes = []string{}
+		for _, scope := range strings.Split(strings.Join(scopes, ","), ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				access.Scopes = append(access.Scopes, scope)
+			}
+		}
+	}
+	if repository.Permissions != nil {
+		access.Push = &repository.Permissions.Push
+	}
+	return access, nil
+}
+
+func (c *GraphQLGitHubClient) CheckAccess(ctx context.Context) (TokenAccess, error) {
+	return c.rest.CheckAccess(ctx)
+}
+
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+type doctorCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// doctor checks the token, remote, branches and templates without touching
+// the release pull request.
+func (s *Service) doctor(ctx context.Context) error {
+	var checks []doctorCheck
+	add := func(name, status, detail string) {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	if s.config.Repository.FullName() == "" {
+		add("repository", doctorFail, "owner and name are not set")
+	} else {
+		add("repository", doctorOK, fmt.Sprintf("%s on %s", s.config.Repository.FullName(), s.config.Repository.ProviderName()))
+	}
+
+	remote, err := s.git.ResolveRemote(ctx, s.config.RemoteName)
+	switch {
+	case err != nil:
+		add("remote", doctorFail, err.Error())
+	case !strings.EqualFold(remote.FullName(), s.config.Repository.FullName()):
+		add("remote", doctorWarn, fmt.Sprintf("%s points to %s, not %s", s.config.RemoteName, remote.FullName(), s.config.Repository.FullName()))
+	default:
+		add("remote", doctorOK, s.config.RemoteName)
+	}
+
+	add(s.checkToken(ctx))
+
+	fetched := s.config.NoFetch
+	if !fetched {
+		if err := s.git.RemoteUpdate(ctx, s.config.RemoteName); err != nil {
+			add("fetch", doctorFail, err.Error())
+		} else {
+			fetched = true
+		}
+	}
+	if fetched {
+		for _, branch := range s.config.doctorBranches() {
+			ref := s.config.remoteBranch(branch)
+			if sha, err := s.git.ResolveRevision(ctx, ref); err != nil {
+				add("branch "+branch, doctorFail, ref+" not found")
+			} else {
+				add("branch "+branch, doctorOK, ref+" at "+shortSHA(sha))
+			}
+		}
+	}
+
+	root, err := s.git.Root(ctx)
+	if err != nil {
+		add("templates", doctorFail, err.Error())
+	} else {
+		for _, template := range s.config.doctorTemplates() {
+			if _, _, err := renderTitleAndBody(root, template.path, template.fallback, TemplateData{}); err != nil {
+				add(template.name, doctorFail, err.Error())
+			} else if template.path == "" {
+				add(template.name, doctorOK, "built-in default")
+			} else {
+				add(template.name, doctorOK, template.path)
+			}
+		}
+	}
+
+	if _, err := s.config.pathFilter(); err != nil {
+		add("path filter", doctorFail, err.Error())
+	}
+
+	failed := false
+	for _, check := range checks {
+		failed = failed || check.Status == doctorFail
+	}
+	if s.config.JSON {
+		encoder := json.NewEncoder(s.stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(checks)
+	} else {
+		for _, check := range checks {
+			line := fmt.Sprintf("%-4s %s", check.Status, check.Name)
+			if check.Detail != "" {
+				line += ": " + check.Detail
+			}
+			fmt.Fprintln(s.stdout, line)
+		}
+	}
+	if failed {
+		return ErrDoctorFailed
+	}
+	return nil
+}
+
+func (s *Service) checkToken(ctx context.Context) (string, string, string) {
+	checker, ok := s.github.(AccessChecker)
+	if !ok {
+		return "token", doctorSkip, "not supported for " + s.config.Repository.ProviderName()
+	}
+	access, err := checker.CheckAccess(ctx)
+	if err != nil {
+		return "token", doctorFail, err.Error()
+	}
+	if access.Scopes != nil && !slices.Contains(access.Scopes, "repo") && !slices.Contains(access.Scopes, "public_repo") {
+		return "token", doctorFail, fmt.Sprintf("missing repo scope (scopes: %s)", strings.Join(access.Scopes, ", "))
+	}
+	if access.Push != nil && !*access.Push {
+		return "token", doctorWarn, "no write access; labels, assignees and reviewers may fail"
+	}
+	if access.Scopes != nil {
+		return "token", doctorOK, "scopes: " + strings.Join(access.Scopes, ", ")
+	}
+	return "token", doctorOK, "repository is readable"
+}
+
+func (c Config) doctorBranches() []string {
+	branches := []string{c.ProductionBranch, c.StagingBranch}
+	for _, stage := range c.Stages {
+		branches = append(branches, stage.ProductionBranch, stage.StagingBranch)
+	}
+	return uniqueStrings(branches)
+}
+
+type doctorTemplate struct {
+	name     string
+	path     string
+	fallback string
+}
+
+func (c Config) doctorTemplates() []doctorTemplate {
+	templates := []doctorTemplate{{name: "template", path: c.TemplatePath, fallback: DefaultTemplate}}
+	for _, stage := range c.Stages {
+		if stage.TemplatePath != "" {
+			templates = append(templates, doctorTemplate{name: "template " + stage.Name, path: stage.TemplatePath, fallback: DefaultTemplate})
+		}
+	}
+	if c.ReleaseTemplatePath != "" {
+		templates = append(templates, doctorTemplate{name: "release template", path: c.ReleaseTemplatePath, fallback: DefaultReleaseNotesTemplate})
+	}
+	if c.ChangelogTemplatePath != "" {
+		templates = append(templates, doctorTemplate{name: "changelog template", path: c.ChangelogTemplatePath, fallback: DefaultChangelogTemplate})
+	}
+	return templates
+}
@@ -0,0 +1,106 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServiceDoctor(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	writeFile(t, filepath.Join(workDir, "broken.tmpl"), "{{ .Missing")
+	runGit(t, workDir, "remote", "set-url", "origin", "git@github.com:octo/fork.git")
+
+	var stdout bytes.Buffer
+	service := NewServiceWithClients(Config{
+		Command:          CommandDoctor,
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "develop",
+		TemplatePath:     "broken.tmpl",
+		NoFetch:          true,
+	}, NewGit(workDir), &fakeGitHubClient{}, &stdout, io.Discard)
+
+	err := service.Run(context.Background())
+	if !errors.Is(err, ErrDoctorFailed) {
+		t.Fatalf("expected doctor failure, got %v", err)
+	}
+
+	output := stdout.String()
+	for _, want := range []string{
+		"warn remote: origin points to",
+		"skip token: not supported for github",
+		"ok   branch master: origin/master at ",
+		"fail branch develop: origin/develop not found",
+		"fail template: ",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestRESTGitHubClientCheckAccess(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/octo/example" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, workflow")
+		_, _ = w.Write([]byte(`{"permissions": {"push": true}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	client.token = "secret"
+	access, err := client.CheckAccess(context.Background())
+	if err != nil {
+		t.Fatalf("check access: %v", err)
+	}
+	if !reflect.DeepEqual(access.Scopes, []string{"repo", "workflow"}) || access.Push == nil || !*access.Push {
+		t.Fatalf("unexpected access: %+v", access)
+	}
+}
+
+func TestRESTGitHubClientCheckAccessRetriesOnRateLimit(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "secondary rate limit", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"permissions": {"push": false}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	access, err := client.CheckAccess(context.Background())
+	if err != nil {
+		t.Fatalf("check access: %v", err)
+	}
+	if access.Scopes != nil || access.Push == nil || *access.Push {
+		t.Fatalf("unexpected access: %+v", access)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+}
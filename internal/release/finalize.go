@@ -0,0 +1,245 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrReleaseNotReady is returned by --check and --finalize while the release
+// pull request still has unchecked items or cannot be merged.
+var ErrReleaseNotReady = errors.New("release pull request is not ready to merge")
+
+const (
+	finalizeDecisionPending          = "pending"
+	finalizeDecisionBlocked          = "blocked"
+	finalizeDecisionReady            = "ready"
+	finalizeDecisionMerged           = "merged"
+	finalizeDecisionAutoMergeEnabled = "auto_merge_enabled"
+)
+
+type checklistItemStatus struct {
+	Number  int      `json:"number,omitempty"`
+	Text    string   `json:"text"`
+	Checked bool     `json:"checked"`
+	Pending []string `json:"pending_users,omitempty"`
+}
+
+type checklistProgress struct {
+	Checked      int                   `json:"checked"`
+	Unchecked    int                   `json:"unchecked"`
+	Items        []checklistItemStatus `json:"items"`
+	PendingUsers []string              `json:"pending_users,omitempty"`
+}
+
+type finalizeResult struct {
+	Decision           string       `json:"decision"`
+	Reasons            []string     `json:"reasons,omitempty"`
+	ReleasePullRequest *PullRequest `json:"release_pull_request"`
+	checklistProgress
+	MergeStatus *MergeStatus `json:"merge_status,omitempty"`
+}
+
+// finalize reports the checklist of the open release pull request and, with
+// --finalize, merges it or enables auto-merge once every item is checked.
+func (s *Service) finalize(ctx context.Context) error {
+	client, ok := s.github.(MergeClient)
+	if !ok {
+		return fmt.Errorf("finalize is not supported for %s", s.config.Repository.ProviderName())
+	}
+
+	releasePR, err := s.detectExistingReleasePullRequest(ctx)
+	if err != nil {
+		return err
+	}
+	if releasePR == nil {
+		return fmt.Errorf("no open release pull request from %s to %s", s.config.StagingBranch, s.config.ProductionBranch)
+	}
+
+	progress, err := s.checklistProgress(ctx, releasePR.Body)
+	if err != nil {
+		return err
+	}
+	result := finalizeResult{ReleasePullRequest: releasePR, checklistProgress: progress}
+	s.say(fmt.Sprintf("Release PR #%d: %d checked, %d unchecked", releasePR.Number, result.Checked, result.Unchecked))
+	for _, line := range progress.uncheckedLines() {
+		s.say(line)
+	}
+
+	if result.Unchecked > 0 {
+		result.Decision = finalizeDecisionPending
+		return s.finishFinalize(result)
+	}
+	// Nobody can sign off on a release without items, e.g. from a template
+	// without checkboxes or when every pull request is excluded by label.
+	if result.Checked == 0 {
+		result.Decision = finalizeDecisionBlocked
+		result.Reasons = []string{"the release pull request has no checklist items"}
+		return s.finishFinalize(result)
+	}
+
+	status, err := client.GetMergeStatus(ctx, releasePR.Number)
+	if err != nil {
+		return err
+	}
+	result.MergeStatus = &status
+	result.Reasons = mergeBlockers(status, s.config.AutoMerge)
+	if len(result.Reasons) > 0 {
+		result.Decision = finalizeDecisionBlocked
+		return s.finishFinalize(result)
+	}
+
+	if !s.config.Finalize || s.config.DryRun {
+		result.Decision = finalizeDecisionReady
+		return s.finishFinalize(result)
+	}
+
+	method := s.config.MergeMethod
+	if method == "" {
+		method = MergeMethodMerge
+	}
+	if s.config.AutoMerge {
+		if !status.AutoMerge {
+			if err := client.EnableAutoMerge(ctx, releasePR.Number, method, status.HeadSHA); err != nil {
+				return err
+			}
+		}
+		result.Decision = finalizeDecisionAutoMergeEnabled
+		return s.finishFinalize(result)
+	}
+	if err := client.MergePullRequest(ctx, releasePR.Number, method, status.HeadSHA); err != nil {
+		return err
+	}
+	result.Decision = finalizeDecisionMerged
+	return s.finishFinalize(result)
+}
+
+// checklistProgress reads the task items of body. Unchecked items wait for
+// the users the pull request they belong to would mention; items pointing at
+// issues or unknown numbers wait for nobody in particular.
+func (s *Service) checklistProgress(ctx context.Context, body string) (checklistProgress, error) {
+	body, _ = extractManualRegions(body)
+	var owners []int
+	var items []taskListItem
+	for _, item := range parseTaskList(splitLines(body), s.config.Repository) {
+		if !item.task() {
+			continue
+		}
+		items = append(items, item)
+		if !item.checked() && item.owner > 0 {
+			owners = append(owners, item.owner)
+		}
+	}
+
+	users := map[int][]string{}
+	if len(owners) > 0 {
+		pullRequests, err := s.github.GetPullRequests(ctx, uniqueInts(owners))
+		if err != nil {
+			return checklistProgress{}, err
+		}
+		for _, pr := range pullRequests {
+			users[pr.Number] = pr.TargetUserLoginNames(s.config.Mention)
+		}
+	}
+
+	progress := checklistProgress{Items: make([]checklistItemStatus, 0, len(items))}
+	pending := map[string]bool{}
+	for _, item := range items {
+		status := checklistItemStatus{Number: item.number, Text: item.text, Checked: item.checked()}
+		if status.Checked {
+			progress.Checked++
+		} else {
+			progress.Unchecked++
+			status.Pending = users[item.owner]
+			for _, user := range status.Pending {
+				pending[user] = true
+			}
+		}
+		progress.Items = append(progress.Items, status)
+	}
+	for user := range pending {
+		progress.PendingUsers = append(progress.PendingUsers, user)
+	}
+	sort.Strings(progress.PendingUsers)
+	return progress, nil
+}
+
+func (p checklistProgress) uncheckedLines() []string {
+	var lines []string
+	for _, item := range p.Items {
+		if item.Checked {
+			continue
+		}
+		line := "  - [ ] " + item.Text
+		if len(item.Pending) > 0 {
+			line += " (waiting for " + strings.Join(item.Pending, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// mergeBlockers lists why the pull request cannot be merged. Auto-merge waits
+// for pending checks and reviews itself, so only failures block it.
+func mergeBlockers(status MergeStatus, autoMerge bool) []string {
+	var reasons []string
+	if status.Mergeable == "CONFLICTING" || status.MergeState == "DIRTY" {
+		reasons = append(reasons, "the pull request has merge conflicts")
+	}
+	if status.ReviewDecision == "CHANGES_REQUESTED" {
+		reasons = append(reasons, "changes are requested")
+	}
+	if len(status.FailingChecks) > 0 {
+		reasons = append(reasons, "required checks are failing: "+strings.Join(status.FailingChecks, ", "))
+	}
+	if autoMerge || len(reasons) > 0 {
+		return reasons
+	}
+
+	if status.ReviewDecision == "REVIEW_REQUIRED" {
+		reasons = append(reasons, "an approving review is required")
+	}
+	if len(status.PendingChecks) > 0 {
+		reasons = append(reasons, "required checks are pending: "+strings.Join(status.PendingChecks, ", "))
+	}
+	switch status.MergeState {
+	case "BEHIND":
+		reasons = append(reasons, "the branch is behind its base branch")
+	case "DRAFT":
+		reasons = append(reasons, "the pull request is a draft")
+	case "BLOCKED":
+		if len(reasons) == 0 {
+			reasons = append(reasons, "the pull request is blocked by branch protection")
+		}
+	}
+	return reasons
+}
+
+func (s *Service) finishFinalize(result finalizeResult) error {
+	switch result.Decision {
+	case finalizeDecisionPending:
+		s.say("Waiting for unchecked items")
+	case finalizeDecisionBlocked:
+		s.say("Not merging: " + strings.Join(result.Reasons, "; "))
+	case finalizeDecisionReady:
+		s.say("All items are checked and the pull request can be merged")
+	case finalizeDecisionMerged:
+		s.say(fmt.Sprintf("Merged pull request: %s", result.ReleasePullRequest.URL))
+	case finalizeDecisionAutoMergeEnabled:
+		s.say(fmt.Sprintf("Enabled auto-merge: %s", result.ReleasePullRequest.URL))
+	}
+
+	if s.config.JSON {
+		encoder := json.NewEncoder(s.stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+	}
+	s.reportFinalizeActions(result)
+	if result.Decision == finalizeDecisionPending || result.Decision == finalizeDecisionBlocked {
+		return ErrReleaseNotReady
+	}
+	return nil
+}
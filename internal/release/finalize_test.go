@@ -0,0 +1,221 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type fakeMergeClient struct {
+	*fakeGitHubClient
+
+	status MergeStatus
+
+	merges     []string
+	autoMerges []string
+}
+
+func (f *fakeMergeClient) GetMergeStatus(_ context.Context, number int) (MergeStatus, error) {
+	return f.status, nil
+}
+
+func (f *fakeMergeClient) MergePullRequest(_ context.Context, number int, method, headSHA string) error {
+	f.merges = append(f.merges, method+"@"+headSHA)
+	return nil
+}
+
+func (f *fakeMergeClient) EnableAutoMerge(_ context.Context, number int, method, headSHA string) error {
+	f.autoMerges = append(f.autoMerges, method+"@"+headSHA)
+	return nil
+}
+
+func TestServiceFinalize(t *testing.T) {
+	t.Parallel()
+
+	const checkedBody = "- [x] #1 Add search @alice\n  - [x] migrated\n* [X] #2 Fix typo @bob"
+	clean := MergeStatus{HeadSHA: "abc", Mergeable: "MERGEABLE", MergeState: "CLEAN", ReviewDecision: "APPROVED"}
+
+	tests := []struct {
+		name           string
+		body           string
+		status         MergeStatus
+		config         Config
+		wantDecision   string
+		wantPending    []string
+		wantReasons    []string
+		wantMerges     []string
+		wantAutoMerges []string
+	}{
+		{
+			name:         "unchecked items",
+			body:         "- [x] #1 Add search @alice\n  - [ ] migrated\n- [ ] #2 Fix typo @bob",
+			status:       clean,
+			config:       Config{Finalize: true},
+			wantDecision: finalizeDecisionPending,
+			wantPending:  []string{"alice", "bob"},
+		},
+		{
+			name:         "unchecked item for an issue",
+			body:         "- [x] #1 Add search @alice\n- [ ] #45 Follow-up",
+			status:       clean,
+			config:       Config{Finalize: true},
+			wantDecision: finalizeDecisionPending,
+		},
+		{
+			name:         "no checklist items",
+			body:         "Release notes without checkboxes\n\n- #1 Add search",
+			status:       clean,
+			config:       Config{Finalize: true},
+			wantDecision: finalizeDecisionBlocked,
+			wantReasons:  []string{"the release pull request has no checklist items"},
+		},
+		{
+			name:         "merge when everything is checked",
+			body:         checkedBody,
+			status:       clean,
+			config:       Config{Finalize: true, MergeMethod: MergeMethodSquash},
+			wantDecision: finalizeDecisionMerged,
+			wantMerges:   []string{"squash@abc"},
+		},
+		{
+			name:         "check only reports",
+			body:         checkedBody,
+			status:       clean,
+			config:       Config{Check: true},
+			wantDecision: finalizeDecisionReady,
+		},
+		{
+			name:         "failing required checks",
+			body:         checkedBody,
+			status:       MergeStatus{HeadSHA: "abc", Mergeable: "MERGEABLE", MergeState: "BLOCKED", FailingChecks: []string{"test"}},
+			config:       Config{Finalize: true, AutoMerge: true},
+			wantDecision: finalizeDecisionBlocked,
+			wantReasons:  []string{"required checks are failing: test"},
+		},
+		{
+			name:         "review required without auto-merge",
+			body:         checkedBody,
+			status:       MergeStatus{HeadSHA: "abc", Mergeable: "MERGEABLE", MergeState: "BLOCKED", ReviewDecision: "REVIEW_REQUIRED", PendingChecks: []string{"lint"}},
+			config:       Config{Finalize: true},
+			wantDecision: finalizeDecisionBlocked,
+			wantReasons:  []string{"an approving review is required", "required checks are pending: lint"},
+		},
+		{
+			name:           "auto-merge waits for pending checks",
+			body:           checkedBody,
+			status:         MergeStatus{HeadSHA: "abc", Mergeable: "MERGEABLE", MergeState: "BLOCKED", PendingChecks: []string{"lint"}},
+			config:         Config{Finalize: true, AutoMerge: true},
+			wantDecision:   finalizeDecisionAutoMergeEnabled,
+			wantAutoMerges: []string{"merge@abc"},
+		},
+		{
+			name:         "auto-merge already enabled",
+			body:         checkedBody,
+			status:       MergeStatus{HeadSHA: "abc", Mergeable: "MERGEABLE", MergeState: "BLOCKED", AutoMerge: true},
+			config:       Config{Finalize: true, AutoMerge: true},
+			wantDecision: finalizeDecisionAutoMergeEnabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeMergeClient{
+				fakeGitHubClient: &fakeGitHubClient{
+					pullRequests: map[int]PullRequest{
+						1: {Number: 1, Merged: true, User: User{LoginName: "alice"}},
+						2: {Number: 2, Merged: true, User: User{LoginName: "bob"}},
+					},
+					releasePullRequests: []PullRequest{{Number: 99, Body: tt.body, URL: "https://example.com/pulls/99"}},
+				},
+				status: tt.status,
+			}
+			config := tt.config
+			config.Repository = Repository{Owner: "octo", Name: "example"}
+			config.ProductionBranch = "master"
+			config.StagingBranch = "staging"
+			config.Mention = "author"
+			config.JSON = true
+
+			var stdout bytes.Buffer
+			err := NewServiceWithClients(config, nil, client, &stdout, io.Discard).Run(context.Background())
+			wantNotReady := tt.wantDecision == finalizeDecisionPending || tt.wantDecision == finalizeDecisionBlocked
+			if wantNotReady != errors.Is(err, ErrReleaseNotReady) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var result finalizeResult
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("decode result: %v\n%s", err, stdout.String())
+			}
+			if result.Decision != tt.wantDecision {
+				t.Fatalf("got decision %q, want %q", result.Decision, tt.wantDecision)
+			}
+			if !reflect.DeepEqual(result.PendingUsers, tt.wantPending) {
+				t.Fatalf("got pending users %v, want %v", result.PendingUsers, tt.wantPending)
+			}
+			if !reflect.DeepEqual(result.Reasons, tt.wantReasons) {
+				t.Fatalf("got reasons %q, want %q", result.Reasons, tt.wantReasons)
+			}
+			if !reflect.DeepEqual(client.merges, tt.wantMerges) || !reflect.DeepEqual(client.autoMerges, tt.wantAutoMerges) {
+				t.Fatalf("got merges %v auto-merges %v", client.merges, client.autoMerges)
+			}
+		})
+	}
+}
+
+func TestRESTGitHubClientGetMergeStatusUsesGraphQL(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+		var request struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		if !strings.Contains(request.Query, "statusCheckRollup") {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"repository": {"pullRequest": {
+  "mergeable": "MERGEABLE",
+  "mergeStateStatus": "BLOCKED",
+  "reviewDecision": "APPROVED",
+  "autoMergeRequest": null,
+  "commits": {"nodes": [{"commit": {"oid": "abc", "statusCheckRollup": {"contexts": {"nodes": [
+    {"__typename": "CheckRun", "name": "test", "status": "COMPLETED", "conclusion": "FAILURE", "isRequired": true},
+    {"__typename": "CheckRun", "name": "lint", "status": "IN_PROGRESS", "isRequired": true},
+    {"__typename": "CheckRun", "name": "optional", "status": "COMPLETED", "conclusion": "FAILURE", "isRequired": false},
+    {"__typename": "StatusContext", "context": "ci/legacy", "state": "ERROR", "isRequired": true}
+  ]}}}}]}
+}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	status, err := newTestGitHubClient(server).GetMergeStatus(context.Background(), 99)
+	if err != nil {
+		t.Fatalf("get merge status: %v", err)
+	}
+	want := MergeStatus{
+		HeadSHA:        "abc",
+		Mergeable:      "MERGEABLE",
+		MergeState:     "BLOCKED",
+		ReviewDecision: "APPROVED",
+		FailingChecks:  []string{"test", "ci/legacy"},
+		PendingChecks:  []string{"lint"},
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Fatalf("got %+v, want %+v", status, want)
+	}
+}
@@ -15,7 +15,55 @@ import (
 
 const DefaultRemoteName = "origin"
 
-var prRefPattern = regexp.MustCompile(`^refs/pull/(\d+)/head$`)
+var prRefPattern = regexp.MustCompile(`^refs/(?:pull|merge-requests)/(\d+)/head$`)
+
+var knownProviderHosts = map[string]string{
+	"gitlab.com":   ProviderGitLab,
+	"gitea.com":    ProviderGitea,
+	"codeberg.org": ProviderGitea,
+}
+
+const (
+	GitBackendExec  = "exec"
+	GitBackendGoGit = "go-git"
+)
+
+// GitBackend is the subset of git operations the release flow depends on.
+// Git shells out to the git binary; GoGit works in-process.
+type GitBackend interface {
+	Root(ctx context.Context) (string, error)
+	ResolveRemote(ctx context.Context, remoteName string) (Repository, error)
+	LookupProjectConfig(ctx context.Context, repo Repository, key string) (string, bool, error)
+	ProjectConfigKeys(ctx context.Context) ([]string, error)
+	LookupConfig(ctx context.Context, key string) (string, bool, error)
+	IsShallow(ctx context.Context) (bool, error)
+	Unshallow(ctx context.Context) error
+	RemoteUpdate(ctx context.Context, remoteName string) error
+	MergedPRNumbers(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]int, error)
+	SquashCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error)
+	MergeCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error)
+	FirstParentCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error)
+	FirstParentCommits(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]Commit, error)
+	MergedTags(ctx context.Context, revision string) ([]string, error)
+	ResolveRevision(ctx context.Context, revision string) (string, error)
+}
+
+type Commit struct {
+	SHA     string
+	Subject string
+	Merge   bool
+}
+
+func NewGitBackend(config Config) GitBackend {
+	if config.GitBackend == GitBackendGoGit {
+		git := NewGoGit(config.WorkDir)
+		if config.Token != "" {
+			git.tokens = staticTokenSource(config.Token)
+		}
+		return git
+	}
+	return NewGit(config.WorkDir)
+}
 
 type Git struct {
 	Dir string
@@ -73,20 +121,37 @@ func (g *Git) Root(ctx context.Context) (string, error) {
 }
 
 func (g *Git) ResolveRemote(ctx context.Context, remoteName string) (Repository, error) {
+	return resolveRemote(ctx, g, remoteName)
+}
+
+func resolveRemote(ctx context.Context, backend GitBackend, remoteName string) (Repository, error) {
 	if remoteName == "" {
 		remoteName = DefaultRemoteName
 	}
-	remoteURL, ok, err := g.LookupConfig(ctx, "remote."+remoteName+".url")
+	remoteURL, ok, err := backend.LookupConfig(ctx, "remote."+remoteName+".url")
 	if err != nil {
 		return Repository{}, err
 	}
 	if !ok || remoteURL == "" {
 		return Repository{}, fmt.Errorf("git remote %q is not configured", remoteName)
 	}
-	return ParseRemoteURL(remoteURL)
+
+	repo, err := ParseRemoteURL(remoteURL)
+	if err != nil || repo.Host == "" {
+		return repo, err
+	}
+	provider, ok, err := backend.LookupConfig(ctx, "pr-release."+repo.Host+".provider")
+	if err != nil || !ok {
+		return repo, err
+	}
+	return parseRemoteURL(remoteURL, provider)
 }
 
 func ParseRemoteURL(raw string) (Repository, error) {
+	return parseRemoteURL(raw, "")
+}
+
+func parseRemoteURL(raw string, provider string) (Repository, error) {
 	remote := strings.TrimSpace(raw)
 	if remote == "" {
 		return Repository{}, errors.New("remote url is empty")
@@ -118,11 +183,33 @@ func ParseRemoteURL(raw string) (Repository, error) {
 		scheme = "https"
 	}
 
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	if provider == "" {
+		provider = knownProviderHosts[host]
+	}
+	switch provider {
+	case "", ProviderGitHub:
+		provider = ""
+	case ProviderGitea, "forgejo":
+		provider = ProviderGitea
+	case ProviderGitLab:
+		return Repository{
+			Host:     host,
+			Scheme:   scheme,
+			Owner:    strings.Join(parts[:len(parts)-1], "/"),
+			Name:     parts[len(parts)-1],
+			Provider: provider,
+		}, nil
+	default:
+		return Repository{}, fmt.Errorf("unsupported provider %q for %s", provider, host)
+	}
+
 	return Repository{
-		Host:   host,
-		Scheme: scheme,
-		Owner:  parts[0],
-		Name:   parts[1],
+		Host:     host,
+		Scheme:   scheme,
+		Owner:    parts[0],
+		Name:     parts[1],
+		Provider: provider,
 	}, nil
 }
 
@@ -158,6 +245,23 @@ func (g *Git) LookupProjectConfig(ctx context.Context, repo Repository, key stri
 	return g.LookupConfig(ctx, hostAwareKey)
 }
 
+// ProjectConfigKeys lists the pr-release.* keys set in .git-pr-release.
+func (g *Git) ProjectConfigKeys(ctx context.Context) ([]string, error) {
+	root, err := g.Root(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projectConfigPath := filepath.Join(root, ".git-pr-release")
+	if _, err := os.Stat(projectConfigPath); err != nil {
+		return nil, nil
+	}
+	value, ok, err := g.lookupConfigWithArgs(ctx, "-f", projectConfigPath, "--name-only", "--get-regexp", `^pr-release\.`)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return strings.Split(value, "\n"), nil
+}
+
 func (g *Git) LookupConfig(ctx context.Context, key string) (string, bool, error) {
 	return g.lookupConfigWithArgs(ctx, key)
 }
@@ -234,7 +338,7 @@ func (g *Git) MergedPRNumbers(ctx context.Context, remoteName, productionBranch,
 		featureShas[fields[1]] = struct{}{}
 	}
 
-	refLines, err := g.Lines(ctx, "ls-remote", remoteName, "refs/pull/*/head")
+	refLines, err := g.Lines(ctx, "ls-remote", remoteName, "refs/pull/*/head", "refs/merge-requests/*/head")
 	if err != nil {
 		return nil, err
 	}
@@ -289,6 +393,71 @@ func (g *Git) SquashCommitSHAs(ctx context.Context, remoteName, productionBranch
 	)
 }
 
+func (g *Git) MergeCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	return g.Lines(
+		ctx,
+		"log",
+		"--merges",
+		"--pretty=format:%H",
+		fmt.Sprintf("%s/%s..%s/%s", remoteName, productionBranch, remoteName, stagingBranch),
+	)
+}
+
+func (g *Git) FirstParentCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	return g.Lines(
+		ctx,
+		"log",
+		"--pretty=format:%H",
+		"--no-merges",
+		"--first-parent",
+		fmt.Sprintf("%s/%s..%s/%s", remoteName, productionBranch, remoteName, stagingBranch),
+	)
+}
+
+func (g *Git) FirstParentCommits(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]Commit, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	lines, err := g.Lines(
+		ctx,
+		"log",
+		"--pretty=format:%H%x00%P%x00%s",
+		"--first-parent",
+		fmt.Sprintf("%s/%s..%s/%s", remoteName, productionBranch, remoteName, stagingBranch),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]Commit, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		commits = append(commits, Commit{
+			SHA:     fields[0],
+			Subject: fields[2],
+			Merge:   len(strings.Fields(fields[1])) > 1,
+		})
+	}
+	return commits, nil
+}
+
+func (g *Git) MergedTags(ctx context.Context, revision string) ([]string, error) {
+	return g.Lines(ctx, "tag", "--merged", revision)
+}
+
+func (g *Git) ResolveRevision(ctx context.Context, revision string) (string, error) {
+	return g.Output(ctx, "rev-parse", "--verify", revision+"^{commit}")
+}
+
 func parsePullRequestRef(ref string) (int, bool) {
 	matches := prRefPattern.FindStringSubmatch(ref)
 	if len(matches) != 2 {
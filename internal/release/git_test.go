@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -35,6 +36,16 @@ func TestParseRemoteURL(t *testing.T) {
 			rawURL: "ssh://git@ghe.example.com/octo/release-tool.git",
 			want:   Repository{Host: "ghe.example.com", Scheme: "https", Owner: "octo", Name: "release-tool"},
 		},
+		{
+			name:   "codeberg",
+			rawURL: "https://codeberg.org/octo/release-tool.git",
+			want:   Repository{Host: "codeberg.org", Scheme: "https", Owner: "octo", Name: "release-tool", Provider: ProviderGitea},
+		},
+		{
+			name:   "gitlab nested group",
+			rawURL: "git@gitlab.com:group/sub/release-tool.git",
+			want:   Repository{Host: "gitlab.com", Scheme: "https", Owner: "group/sub", Name: "release-tool", Provider: ProviderGitLab},
+		},
 	}
 
 	for _, tt := range tests {
@@ -84,6 +95,56 @@ func TestLookupProjectConfig(t *testing.T) {
 	}
 }
 
+func TestResolveRemoteDetectsProviderFromGitConfig(t *testing.T) {
+	t.Parallel()
+
+	workDir := t.TempDir()
+	runGit(t, workDir, "init")
+	runGit(t, workDir, "remote", "add", "origin", "https://gitlab.example.com/group/sub/example.git")
+	runGit(t, workDir, "config", "pr-release.gitlab.example.com.provider", "gitlab")
+
+	repo, err := NewGit(workDir).ResolveRemote(context.Background(), DefaultRemoteName)
+	if err != nil {
+		t.Fatalf("resolve remote: %v", err)
+	}
+
+	want := Repository{Host: "gitlab.example.com", Scheme: "https", Owner: "group/sub", Name: "example", Provider: ProviderGitLab}
+	if !reflect.DeepEqual(repo, want) {
+		t.Fatalf("got %+v, want %+v", repo, want)
+	}
+	if got := repo.APIBaseURL(); got != "https://gitlab.example.com/api/v4/" {
+		t.Fatalf("unexpected api base url: %q", got)
+	}
+	if got := repo.HeadRef("staging"); got != "staging" {
+		t.Fatalf("unexpected head ref: %q", got)
+	}
+}
+
+func TestMergedPRNumbersIncludesGitLabMergeRequestRefs(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "checkout", "-b", "feature3", "origin/master")
+	writeFile(t, filepath.Join(workDir, "feature3.txt"), "feature3\n")
+	runGit(t, workDir, "add", "feature3.txt")
+	runGit(t, workDir, "commit", "-m", "feature3")
+	runGit(t, workDir, "push", "origin", "HEAD:refs/merge-requests/3/head")
+	runGit(t, workDir, "checkout", "staging")
+	runGit(t, workDir, "merge", "--no-ff", "feature3", "-m", "Merge branch 'feature3' into 'staging'")
+	runGit(t, workDir, "push", "origin", "staging")
+	runGit(t, workDir, "fetch", "origin")
+
+	got, err := NewGit(workDir).MergedPRNumbers(context.Background(), DefaultRemoteName, "master", "staging")
+	if err != nil {
+		t.Fatalf("merged pr numbers: %v", err)
+	}
+
+	sort.Ints(got)
+	if want := []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestMergedPRNumbers(t *testing.T) {
 	t.Parallel()
 
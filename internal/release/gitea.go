@@ -0,0 +1,302 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const giteaPageSize = 50
+
+type GiteaClient struct {
+	rest       *RESTGitHubClient
+	repository Repository
+}
+
+func NewGiteaClient(config Config) *GiteaClient {
+	return &GiteaClient{
+		rest:       NewRESTGitHubClient(config),
+		repository: config.Repository,
+	}
+}
+
+func (c *GiteaClient) CacheStats() CacheStats {
+	return c.rest.CacheStats()
+}
+
+func (c *GiteaClient) GetPullRequests(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	numbers = uniqueInts(numbers)
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	results := make([]*PullRequest, len(numbers))
+	err := forEachConcurrently(ctx, len(numbers), commitLookupConcurrency, func(ctx context.Context, idx int) error {
+		pr, err := c.getPullRequest(ctx, numbers[idx])
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		results[idx] = pr
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	pullRequests := make([]PullRequest, 0, len(results))
+	for _, pr := range results {
+		if pr != nil {
+			pullRequests = append(pullRequests, *pr)
+		}
+	}
+	return pullRequests, nil
+}
+
+func (c *GiteaClient) getPullRequest(ctx context.Context, number int) (*PullRequest, error) {
+	var response pullRequestDTO
+	if err := c.rest.request(ctx, http.MethodGet, c.repoPath(fmt.Sprintf("pulls/%d", number)), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	pr := response.toDomain()
+	return &pr, nil
+}
+
+func (c *GiteaClient) ListOpenReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error) {
+	if idx := strings.Index(head, ":"); idx >= 0 {
+		head = head[idx+1:]
+	}
+
+	var pullRequests []PullRequest
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("state", "open")
+		query.Set("limit", fmt.Sprintf("%d", giteaPageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []pullRequestDTO
+		if err := c.rest.request(ctx, http.MethodGet, c.repoPath("pulls"), query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, pr := range response {
+			if pr.Head.Ref != head || pr.Base.Ref != base {
+				continue
+			}
+			pullRequests = append(pullRequests, pr.toDomain())
+		}
+
+		if len(response) < giteaPageSize {
+			break
+		}
+	}
+
+	return pullRequests, nil
+}
+
+func (c *GiteaClient) CreatePullRequest(ctx context.Context, title, head, base, body string) (*PullRequest, error) {
+	if idx := strings.Index(head, ":"); idx >= 0 {
+		head = head[idx+1:]
+	}
+
+	request := map[string]string{
+		"title": title,
+		"head":  head,
+		"base":  base,
+		"body":  body,
+	}
+
+	var response pullRequestDTO
+	if err := c.rest.request(ctx, http.MethodPost, c.repoPath("pulls"), nil, request, &response); err != nil {
+		return nil, err
+	}
+
+	pr := response.toDomain()
+	return &pr, nil
+}
+
+func (c *GiteaClient) UpdatePullRequest(ctx context.Context, number int, title, body string) (*PullRequest, error) {
+	request := map[string]string{
+		"title": title,
+		"body":  body,
+	}
+
+	var response pullRequestDTO
+	if err := c.rest.request(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", number)), nil, request, &response); err != nil {
+		return nil, err
+	}
+
+	pr := response.toDomain()
+	return &pr, nil
+}
+
+func (c *GiteaClient) AddLabels(ctx context.Context, number int, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	labelIDs, err := c.lookupLabelIDs(ctx, labels)
+	if err != nil {
+		return err
+	}
+
+	request := map[string][]int64{"labels": labelIDs}
+	return c.rest.request(withIdempotentRequest(ctx), http.MethodPost, c.repoPath(fmt.Sprintf("issues/%d/labels", number)), nil, request, nil)
+}
+
+func (c *GiteaClient) AddAssignees(ctx context.Context, number int, assignees []string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	current, err := c.getPullRequest(ctx, number)
+	if err != nil {
+		return err
+	}
+	merged := make([]string, 0, len(current.Assignees)+len(assignees))
+	for _, assignee := range current.Assignees {
+		merged = append(merged, assignee.LoginName)
+	}
+	merged = uniqueStrings(append(merged, assignees...))
+
+	request := map[string][]string{"assignees": merged}
+	return c.rest.request(ctx, http.MethodPatch, c.repoPath(fmt.Sprintf("pulls/%d", number)), nil, request, nil)
+}
+
+func (c *GiteaClient) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	if len(reviewers) == 0 {
+		return nil
+	}
+	request := map[string][]string{"reviewers": reviewers}
+	return c.rest.request(withIdempotentRequest(ctx), http.MethodPost, c.repoPath(fmt.Sprintf("pulls/%d/requested_reviewers", number)), nil, request, nil)
+}
+
+func (c *GiteaClient) ListPullRequestFiles(ctx context.Context, number int) ([]ChangedFile, error) {
+	var files []ChangedFile
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprintf("%d", giteaPageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []changedFileDTO
+		if err := c.rest.request(ctx, http.MethodGet, c.repoPath(fmt.Sprintf("pulls/%d/files", number)), query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, file := range response {
+			files = append(files, file.toDomain())
+		}
+
+		if len(response) < giteaPageSize {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// SearchPullRequestNumbers resolves the commit SHAs contained in a GitHub
+// style search query, because the Gitea issue search cannot match commits.
+func (c *GiteaClient) SearchPullRequestNumbers(ctx context.Context, query string) ([]int, error) {
+	var numbers []int
+	for _, term := range strings.Fields(query) {
+		if !commitSHAPattern.MatchString(term) {
+			continue
+		}
+		pullRequests, err := c.ListPullRequestsForCommit(ctx, term)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range pullRequests {
+			if !pr.Merged {
+				continue
+			}
+			numbers = append(numbers, pr.Number)
+		}
+	}
+	return uniqueInts(numbers), nil
+}
+
+func (c *GiteaClient) ListPullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	var response pullRequestDTO
+	if err := c.rest.request(ctx, http.MethodGet, c.repoPath("commits/"+url.PathEscape(sha)+"/pull"), nil, nil, &response); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []PullRequest{response.toDomain()}, nil
+}
+
+// lookupLabelIDs resolves names against the repository labels and, for
+// names it lacks, the labels of the owning organization.
+func (c *GiteaClient) lookupLabelIDs(ctx context.Context, names []string) ([]int64, error) {
+	available, err := c.listLabels(ctx, c.repoPath("labels"))
+	if err != nil {
+		return nil, err
+	}
+
+	names = uniqueStrings(names)
+	missing := slices.ContainsFunc(names, func(name string) bool {
+		_, ok := available[name]
+		return !ok
+	})
+	if missing {
+		// User-owned repositories have no organization labels.
+		orgLabels, err := c.listLabels(ctx, fmt.Sprintf("orgs/%s/labels", url.PathEscape(c.repository.Owner)))
+		if err != nil && !isNotFound(err) {
+			return nil, err
+		}
+		for name, id := range orgLabels {
+			if _, ok := available[name]; !ok {
+				available[name] = id
+			}
+		}
+	}
+
+	labelIDs := make([]int64, 0, len(names))
+	for _, name := range names {
+		id, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("label %q not found in %s", name, c.repository.FullName())
+		}
+		labelIDs = append(labelIDs, id)
+	}
+	return labelIDs, nil
+}
+
+func (c *GiteaClient) listLabels(ctx context.Context, path string) (map[string]int64, error) {
+	labels := map[string]int64{}
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("limit", fmt.Sprintf("%d", giteaPageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []giteaLabelDTO
+		if err := c.rest.request(ctx, http.MethodGet, path, query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, label := range response {
+			labels[label.Name] = label.ID
+		}
+
+		if len(response) < giteaPageSize {
+			return labels, nil
+		}
+	}
+}
+
+func (c *GiteaClient) repoPath(suffix string) string {
+	return fmt.Sprintf("repos/%s/%s", c.repository.FullName(), suffix)
+}
+
+type giteaLabelDTO struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
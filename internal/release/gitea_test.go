@@ -0,0 +1,150 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGiteaClientAddLabelsResolvesLabelIDs(t *testing.T) {
+	t.Parallel()
+
+	var applied map[string][]int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/octo/example/labels":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 1 {
+				labels := make([]giteaLabelDTO, 0, giteaPageSize)
+				for id := 1; id <= giteaPageSize; id++ {
+					labels = append(labels, giteaLabelDTO{ID: int64(id), Name: "label" + strconv.Itoa(id)})
+				}
+				labels[0].Name = "release"
+				_ = json.NewEncoder(w).Encode(labels)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]giteaLabelDTO{{ID: 51, Name: "qa"}})
+		case "/api/v1/repos/octo/example/issues/5/labels":
+			_ = json.NewDecoder(r.Body).Decode(&applied)
+			_ = json.NewEncoder(w).Encode([]giteaLabelDTO{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGiteaClient(server)
+
+	if err := client.AddLabels(context.Background(), 5, []string{"release", "qa"}); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+	if got, want := applied["labels"], []int64{1, 51}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	err := client.AddLabels(context.Background(), 5, []string{"missing"})
+	if err == nil || !strings.Contains(err.Error(), `label "missing" not found`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGiteaClientAddLabelsFallsBackToOrganizationLabels(t *testing.T) {
+	t.Parallel()
+
+	var applied map[string][]int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/repos/octo/example/labels":
+			_ = json.NewEncoder(w).Encode([]giteaLabelDTO{{ID: 1, Name: "release"}})
+		case "/api/v1/orgs/octo/labels":
+			_ = json.NewEncoder(w).Encode([]giteaLabelDTO{{ID: 90, Name: "release"}, {ID: 91, Name: "qa"}})
+		case "/api/v1/repos/octo/example/issues/5/labels":
+			_ = json.NewDecoder(r.Body).Decode(&applied)
+			_ = json.NewEncoder(w).Encode([]giteaLabelDTO{})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGiteaClient(server)
+
+	if err := client.AddLabels(context.Background(), 5, []string{"release", "qa"}); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+	if got, want := applied["labels"], []int64{1, 91}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGiteaClientListOpenReleasePullRequestsFiltersByBranches(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/repos/octo/example/pulls" || r.URL.Query().Get("state") != "open" {
+			http.NotFound(w, r)
+			return
+		}
+		prs := []pullRequestDTO{{Number: 1}, {Number: 2}, {Number: 3}}
+		prs[0].Head.Ref, prs[0].Base.Ref = "feature", "staging"
+		prs[1].Head.Ref, prs[1].Base.Ref = "staging", "master"
+		prs[2].Head.Ref, prs[2].Base.Ref = "staging", "develop"
+		_ = json.NewEncoder(w).Encode(prs)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGiteaClient(server)
+
+	pullRequests, err := client.ListOpenReleasePullRequests(context.Background(), "staging", "master")
+	if err != nil {
+		t.Fatalf("list open release pull requests: %v", err)
+	}
+	if got, want := pullRequestNumbers(pullRequests), []int{2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGiteaClientListPullRequestsForCommitIgnoresUnknownCommits(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/repos/octo/example/commits/abcdef0/pull" {
+			_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 7, Merged: true, MergeCommitSHA: "abcdef0"})
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGiteaClient(server)
+
+	numbers, err := client.SearchPullRequestNumbers(context.Background(), "repo:octo/example is:pr is:closed abcdef0 1234567")
+	if err != nil {
+		t.Fatalf("search pull request numbers: %v", err)
+	}
+	if got, want := numbers, []int{7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func newTestGiteaClient(server *httptest.Server) *GiteaClient {
+	parsedURL, err := url.Parse(server.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	repository := Repository{Host: parsedURL.Host, Scheme: parsedURL.Scheme, Owner: "octo", Name: "example", Provider: ProviderGitea}
+	rest := newTestGitHubClient(server)
+	rest.baseURL = server.URL + "/api/v1/"
+	rest.repository = repository
+	return &GiteaClient{
+		rest:       rest,
+		repository: repository,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +25,7 @@ type GitHubClient interface {
 	RequestReviewers(ctx context.Context, number int, reviewers []string) error
 	ListPullRequestFiles(ctx context.Context, number int) ([]ChangedFile, error)
 	SearchPullRequestNumbers(ctx context.Context, query string) ([]int, error)
+	ListPullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error)
 }
 
 type RESTGitHubClient struct {
@@ -31,8 +33,25 @@ type RESTGitHubClient struct {
 	baseURL    string
 	repository Repository
 	token      string
+	tokens     tokenSource
+	cache      *httpCache
+	retry      RetryPolicy
 	sleepFn    func(context.Context, time.Duration) error
 	nowFn      func() time.Time
+	randFn     func() float64
+}
+
+func NewGitHubClient(config Config) GitHubClient {
+	switch {
+	case config.Repository.ProviderName() == ProviderGitLab:
+		return NewGitLabClient(config)
+	case config.Repository.ProviderName() == ProviderGitea:
+		return NewGiteaClient(config)
+	case config.GitHubAPI == GitHubAPIGraphQL:
+		return NewGraphQLGitHubClient(config)
+	default:
+		return NewRESTGitHubClient(config)
+	}
 }
 
 func NewRESTGitHubClient(config Config) *RESTGitHubClient {
@@ -41,7 +60,7 @@ func NewRESTGitHubClient(config Config) *RESTGitHubClient {
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec
 	}
 
-	return &RESTGitHubClient{
+	client := &RESTGitHubClient{
 		httpClient: &http.Client{
 			Timeout:   30 * time.Second,
 			Transport: transport,
@@ -49,9 +68,15 @@ func NewRESTGitHubClient(config Config) *RESTGitHubClient {
 		baseURL:    config.Repository.APIBaseURL(),
 		repository: config.Repository,
 		token:      config.Token,
+		cache:      newHTTPCache(config),
+		retry:      config.Retry,
 		sleepFn:    sleepWithContext,
 		nowFn:      time.Now,
 	}
+	if config.AppID != 0 {
+		client.tokens = newGitHubAppTokenSource(config, client.httpClient)
+	}
+	return client
 }
 
 func (c *RESTGitHubClient) GetPullRequests(ctx context.Context, numbers []int) ([]PullRequest, error) {
@@ -97,6 +122,11 @@ func (c *RESTGitHubClient) GetPullRequests(ctx context.Context, numbers []int) (
 			}
 			pr, err := c.getPullRequest(ctx, number)
 			if err != nil {
+				// Issues and pull requests of other repositories share the
+				// number space; leave them out like the other clients do.
+				if isNotFound(err) {
+					continue
+				}
 				return nil, err
 			}
 			found[number] = *pr
@@ -229,7 +259,7 @@ func (c *RESTGitHubClient) AddLabels(ctx context.Context, number int, labels []s
 	}
 	request := map[string][]string{"labels": labels}
 	return c.request(
-		ctx,
+		withIdempotentRequest(ctx),
 		http.MethodPost,
 		fmt.Sprintf("repos/%s/issues/%d/labels", c.repository.FullName(), number),
 		nil,
@@ -244,7 +274,7 @@ func (c *RESTGitHubClient) AddAssignees(ctx context.Context, number int, assigne
 	}
 	request := map[string][]string{"assignees": assignees}
 	return c.request(
-		ctx,
+		withIdempotentRequest(ctx),
 		http.MethodPost,
 		fmt.Sprintf("repos/%s/issues/%d/assignees", c.repository.FullName(), number),
 		nil,
@@ -259,7 +289,7 @@ func (c *RESTGitHubClient) RequestReviewers(ctx context.Context, number int, rev
 	}
 	request := map[string][]string{"reviewers": reviewers}
 	return c.request(
-		ctx,
+		withIdempotentRequest(ctx),
 		http.MethodPost,
 		fmt.Sprintf("repos/%s/pulls/%d/requested_reviewers", c.repository.FullName(), number),
 		nil,
@@ -327,6 +357,39 @@ func (c *RESTGitHubClient) SearchPullRequestNumbers(ctx context.Context, query s
 	return uniqueInts(numbers), nil
 }
 
+func (c *RESTGitHubClient) ListPullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	const pageSize = 100
+
+	var pullRequests []PullRequest
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("per_page", fmt.Sprintf("%d", pageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []pullRequestDTO
+		if err := c.request(
+			ctx,
+			http.MethodGet,
+			fmt.Sprintf("repos/%s/commits/%s/pulls", c.repository.FullName(), sha),
+			query,
+			nil,
+			&response,
+		); err != nil {
+			return nil, err
+		}
+
+		for _, pr := range response {
+			pullRequests = append(pullRequests, pr.toDomain())
+		}
+
+		if len(response) < pageSize {
+			break
+		}
+	}
+
+	return pullRequests, nil
+}
+
 func (c *RESTGitHubClient) request(
 	ctx context.Context,
 	method string,
@@ -339,19 +402,48 @@ func (c *RESTGitHubClient) request(
 	if err != nil {
 		return fmt.Errorf("parse github base url: %w", err)
 	}
-	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/") + "/" + strings.TrimPrefix(path, "/")
+	endpoint = endpoint.JoinPath(path)
 	endpoint.RawQuery = query.Encode()
 
+	return c.do(ctx, method, endpoint, requestBody, responseBody)
+}
+
+func (c *RESTGitHubClient) do(
+	ctx context.Context,
+	method string,
+	endpoint *url.URL,
+	requestBody any,
+	responseBody any,
+) error {
+	_, err := c.doWithHeaders(ctx, method, endpoint, requestBody, responseBody)
+	return err
+}
+
+// doWithHeaders is do that also returns the response headers. A cache hit
+// returns the headers of the 304 response.
+func (c *RESTGitHubClient) doWithHeaders(
+	ctx context.Context,
+	method string,
+	endpoint *url.URL,
+	requestBody any,
+	responseBody any,
+) (http.Header, error) {
 	var requestPayload []byte
 	if requestBody != nil {
 		var buf bytes.Buffer
 		if err := json.NewEncoder(&buf).Encode(requestBody); err != nil {
-			return fmt.Errorf("encode github request: %w", err)
+			return nil, fmt.Errorf("encode github request: %w", err)
 		}
 		requestPayload = buf.Bytes()
 	}
 
-	const maxRateLimitRetries = 2
+	var cacheKey string
+	var cached *cacheEntry
+	if c.cache != nil && method == http.MethodGet {
+		cacheKey = c.cache.key(endpoint.String())
+		cached, _ = c.cache.load(cacheKey)
+	}
+
 	for attempt := 0; ; attempt++ {
 		var body io.Reader
 		if requestPayload != nil {
@@ -360,50 +452,115 @@ func (c *RESTGitHubClient) request(
 
 		req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
 		if err != nil {
-			return fmt.Errorf("create github request: %w", err)
+			return nil, fmt.Errorf("create github request: %w", err)
 		}
 		req.Header.Set("Accept", "application/vnd.github+json")
 		req.Header.Set("User-Agent", "go-pr-release")
-		if c.token != "" {
-			req.Header.Set("Authorization", "Bearer "+c.token)
+		token, err := c.authToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
 		}
 		if requestPayload != nil {
 			req.Header.Set("Content-Type", "application/json")
 		}
+		if cached != nil {
+			c.cache.applyValidators(req, cached)
+		}
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return fmt.Errorf("%s %s: %w", method, endpoint.Path, err)
+			if decision := c.retryAfterTransportError(ctx, method, err, attempt); decision.retry {
+				if err := c.sleep(ctx, decision.wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, fmt.Errorf("%s %s: %w", method, endpoint.Path, err)
 		}
 
-		if wait, shouldRetry := c.rateLimitRetryDelay(resp); shouldRetry && attempt < maxRateLimitRetries {
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
 			_, _ = io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
-			if err := c.sleep(ctx, wait); err != nil {
-				return err
+			c.cache.hit(cacheKey)
+			if responseBody == nil {
+				return resp.Header, nil
+			}
+			if err := json.Unmarshal(cached.Body, responseBody); err != nil {
+				return nil, fmt.Errorf("decode cached github response: %w", err)
 			}
-			continue
+			return resp.Header, nil
 		}
 
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 			payload, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 			resp.Body.Close()
-			return fmt.Errorf("%s %s: unexpected status %d: %s", method, endpoint.Path, resp.StatusCode, strings.TrimSpace(string(payload)))
+			if decision := c.retryAfterResponse(ctx, method, resp, payload, attempt); decision.retry {
+				if err := c.sleep(ctx, decision.wait); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, &APIError{
+				Method:     method,
+				Path:       endpoint.Path,
+				StatusCode: resp.StatusCode,
+				Message:    strings.TrimSpace(string(payload)),
+			}
+		}
+
+		if cacheKey != "" {
+			payload, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("read github response: %w", err)
+			}
+			// The cache is best effort; a failed write only costs a refetch.
+			_ = c.cache.store(cacheKey, endpoint.String(), resp.Header, payload)
+			if responseBody == nil {
+				return resp.Header, nil
+			}
+			if err := json.Unmarshal(payload, responseBody); err != nil {
+				return nil, fmt.Errorf("decode github response: %w", err)
+			}
+			return resp.Header, nil
 		}
 
 		if responseBody == nil {
 			_, _ = io.Copy(io.Discard, resp.Body)
 			resp.Body.Close()
-			return nil
+			return resp.Header, nil
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(responseBody); err != nil {
 			resp.Body.Close()
-			return fmt.Errorf("decode github response: %w", err)
+			return nil, fmt.Errorf("decode github response: %w", err)
 		}
 		resp.Body.Close()
-		return nil
+		return resp.Header, nil
+	}
+}
+
+func (c *RESTGitHubClient) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.stats()
+}
+
+// Token returns the token requests are authenticated with, so the go-git
+// backend can fetch with the same credentials.
+func (c *RESTGitHubClient) Token(ctx context.Context) (string, error) {
+	return c.authToken(ctx)
+}
+
+func (c *RESTGitHubClient) authToken(ctx context.Context) (string, error) {
+	if c.tokens != nil {
+		return c.tokens.Token(ctx)
 	}
+	return c.token, nil
 }
 
 func (c *RESTGitHubClient) rateLimitRetryDelay(resp *http.Response) (time.Duration, bool) {
@@ -412,11 +569,8 @@ func (c *RESTGitHubClient) rateLimitRetryDelay(resp *http.Response) (time.Durati
 		return 0, false
 	}
 
-	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-		seconds, err := time.ParseDuration(retryAfter + "s")
-		if err == nil {
-			return seconds, true
-		}
+	if wait, ok := retryAfterDelay(resp); ok {
+		return wait, true
 	}
 
 	if reset := resp.Header.Get("X-RateLimit-Reset"); reset != "" {
@@ -470,6 +624,22 @@ func sleepWithContext(ctx context.Context, duration time.Duration) error {
 	}
 }
 
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
 type searchIssuesResponse struct {
 	Items []struct {
 		Number int `json:"number"`
@@ -499,10 +669,17 @@ type pullRequestDTO struct {
 	Merged         bool       `json:"merged"`
 	MergeCommitSHA string     `json:"merge_commit_sha"`
 	MergedAt       *time.Time `json:"merged_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
 	User           userDTO    `json:"user"`
 	Assignee       *userDTO   `json:"assignee"`
 	Assignees      []userDTO  `json:"assignees"`
-	Head           struct {
+	Labels         []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+	Head struct {
 		Ref string `json:"ref"`
 	} `json:"head"`
 	Base struct {
@@ -534,6 +711,12 @@ func (pr pullRequestDTO) toDomain() PullRequest {
 	for _, assignee := range pr.Assignees {
 		domain.Assignees = append(domain.Assignees, assignee.toDomain())
 	}
+	for _, label := range pr.Labels {
+		domain.Labels = append(domain.Labels, label.Name)
+	}
+	if pr.Milestone != nil {
+		domain.Milestone = pr.Milestone.Title
+	}
 	return domain
 }
 
@@ -64,6 +64,30 @@ func TestRESTGitHubClientGetPullRequestsAvoidsOneRequestPerPullRequestBeyondOneT
 	}
 }
 
+func TestRESTGitHubClientGetPullRequestsSkipsNumbersThatAreNotPullRequests(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/octo/example/pulls":
+			_ = json.NewEncoder(w).Encode([]pullRequestDTO{{Number: 1, State: "closed", Merged: true}})
+		case "/api/v3/repos/octo/example/pulls/2":
+			_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 2, State: "open"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	pullRequests, err := newTestGitHubClient(server).GetPullRequests(context.Background(), []int{1, 45, 2})
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+	if got := pullRequestNumbers(pullRequests); !reflect.DeepEqual(got, []int{1, 2}) {
+		t.Fatalf("got %v, want [1 2]", got)
+	}
+}
+
 func TestRESTGitHubClientSearchPullRequestNumbersPaginatesBeyondOneHundredResults(t *testing.T) {
 	t.Parallel()
 
@@ -145,6 +169,66 @@ func TestRESTGitHubClientSearchPullRequestNumbersRetriesOnRateLimit(t *testing.T
 	}
 }
 
+func TestRESTGitHubClientListPullRequestsForCommit(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/octo/example/commits/abcdef0123/pulls" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]pullRequestDTO{
+			{Number: 7, Title: "feature", Merged: true, MergeCommitSHA: "abcdef0123"},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+
+	pullRequests, err := client.ListPullRequestsForCommit(context.Background(), "abcdef0123")
+	if err != nil {
+		t.Fatalf("list pull requests for commit: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if pullRequests[0].MergeCommitSHA != "abcdef0123" {
+		t.Fatalf("unexpected merge commit sha: %q", pullRequests[0].MergeCommitSHA)
+	}
+}
+
+func TestRESTGitHubClientDecodesLabelsAndMilestone(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{
+			"number": 7,
+			"merged": true,
+			"merge_commit_sha": "abcdef0123",
+			"labels": [{"name": "feature"}, {"name": "skip-release-notes"}],
+			"milestone": {"title": "v1.2.0"}
+		}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+
+	pullRequests, err := client.ListPullRequestsForCommit(context.Background(), "abcdef0123")
+	if err != nil {
+		t.Fatalf("list pull requests for commit: %v", err)
+	}
+	if len(pullRequests) != 1 {
+		t.Fatalf("got %d pull requests, want 1", len(pullRequests))
+	}
+	if got, want := pullRequests[0].Labels, []string{"feature", "skip-release-notes"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got labels %v, want %v", got, want)
+	}
+	if pullRequests[0].Milestone != "v1.2.0" {
+		t.Fatalf("unexpected milestone: %q", pullRequests[0].Milestone)
+	}
+}
+
 func newPaginatedPullRequestClient(t *testing.T, total int) (*RESTGitHubClient, *int, *int) {
 	t.Helper()
 
@@ -0,0 +1,393 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+type GitLabClient struct {
+	rest       *RESTGitHubClient
+	repository Repository
+}
+
+func NewGitLabClient(config Config) *GitLabClient {
+	return &GitLabClient{
+		rest:       NewRESTGitHubClient(config),
+		repository: config.Repository,
+	}
+}
+
+func (c *GitLabClient) CacheStats() CacheStats {
+	return c.rest.CacheStats()
+}
+
+func (c *GitLabClient) GetPullRequests(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	numbers = uniqueInts(numbers)
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	const pageSize = 100
+
+	found := make(map[int]PullRequest, len(numbers))
+	for start := 0; start < len(numbers); start += pageSize {
+		end := min(start+pageSize, len(numbers))
+
+		query := url.Values{}
+		query.Set("state", "all")
+		query.Set("per_page", fmt.Sprintf("%d", pageSize))
+		for _, number := range numbers[start:end] {
+			query.Add("iids[]", fmt.Sprintf("%d", number))
+		}
+
+		var response []mergeRequestDTO
+		if err := c.rest.request(ctx, http.MethodGet, c.projectPath("merge_requests"), query, nil, &response); err != nil {
+			return nil, err
+		}
+		for _, mr := range response {
+			found[mr.IID] = mr.toDomain()
+		}
+	}
+
+	pullRequests := make([]PullRequest, 0, len(found))
+	for _, number := range numbers {
+		if pr, ok := found[number]; ok {
+			pullRequests = append(pullRequests, pr)
+		}
+	}
+	return pullRequests, nil
+}
+
+func (c *GitLabClient) ListOpenReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error) {
+	if idx := strings.Index(head, ":"); idx >= 0 {
+		head = head[idx+1:]
+	}
+
+	query := url.Values{}
+	query.Set("state", "opened")
+	query.Set("source_branch", head)
+	query.Set("target_branch", base)
+
+	var response []mergeRequestDTO
+	if err := c.rest.request(ctx, http.MethodGet, c.projectPath("merge_requests"), query, nil, &response); err != nil {
+		return nil, err
+	}
+
+	pullRequests := make([]PullRequest, 0, len(response))
+	for _, mr := range response {
+		pullRequests = append(pullRequests, mr.toDomain())
+	}
+	return pullRequests, nil
+}
+
+func (c *GitLabClient) CreatePullRequest(ctx context.Context, title, head, base, body string) (*PullRequest, error) {
+	if idx := strings.Index(head, ":"); idx >= 0 {
+		head = head[idx+1:]
+	}
+
+	request := map[string]string{
+		"title":         title,
+		"source_branch": head,
+		"target_branch": base,
+		"description":   body,
+	}
+
+	var response mergeRequestDTO
+	if err := c.rest.request(ctx, http.MethodPost, c.projectPath("merge_requests"), nil, request, &response); err != nil {
+		return nil, err
+	}
+
+	pr := response.toDomain()
+	return &pr, nil
+}
+
+func (c *GitLabClient) UpdatePullRequest(ctx context.Context, number int, title, body string) (*PullRequest, error) {
+	request := map[string]string{
+		"title":       title,
+		"description": body,
+	}
+
+	var response mergeRequestDTO
+	if err := c.rest.request(ctx, http.MethodPut, c.mergeRequestPath(number), nil, request, &response); err != nil {
+		return nil, err
+	}
+
+	pr := response.toDomain()
+	return &pr, nil
+}
+
+func (c *GitLabClient) AddLabels(ctx context.Context, number int, labels []string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	request := map[string]string{"add_labels": strings.Join(labels, ",")}
+	return c.rest.request(ctx, http.MethodPut, c.mergeRequestPath(number), nil, request, nil)
+}
+
+func (c *GitLabClient) AddAssignees(ctx context.Context, number int, assignees []string) error {
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	current, err := c.getMergeRequest(ctx, number)
+	if err != nil {
+		return err
+	}
+	userIDs, err := c.lookupUserIDs(ctx, assignees)
+	if err != nil {
+		return err
+	}
+
+	request := map[string][]int{"assignee_ids": mergeUserIDs(current.Assignees, userIDs)}
+	return c.rest.request(ctx, http.MethodPut, c.mergeRequestPath(number), nil, request, nil)
+}
+
+func (c *GitLabClient) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	if len(reviewers) == 0 {
+		return nil
+	}
+
+	current, err := c.getMergeRequest(ctx, number)
+	if err != nil {
+		return err
+	}
+	userIDs, err := c.lookupUserIDs(ctx, reviewers)
+	if err != nil {
+		return err
+	}
+
+	request := map[string][]int{"reviewer_ids": mergeUserIDs(current.Reviewers, userIDs)}
+	return c.rest.request(ctx, http.MethodPut, c.mergeRequestPath(number), nil, request, nil)
+}
+
+func (c *GitLabClient) ListPullRequestFiles(ctx context.Context, number int) ([]ChangedFile, error) {
+	const pageSize = 100
+
+	var files []ChangedFile
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("per_page", fmt.Sprintf("%d", pageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []mergeRequestDiffDTO
+		if err := c.rest.request(ctx, http.MethodGet, c.mergeRequestPath(number)+"/diffs", query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, diff := range response {
+			files = append(files, diff.toDomain())
+		}
+
+		if len(response) < pageSize {
+			break
+		}
+	}
+
+	return files, nil
+}
+
+// SearchPullRequestNumbers resolves the commit SHAs contained in a GitHub
+// style search query, because GitLab has no search qualifier for merge
+// request commits.
+func (c *GitLabClient) SearchPullRequestNumbers(ctx context.Context, query string) ([]int, error) {
+	var numbers []int
+	for _, term := range strings.Fields(query) {
+		if !commitSHAPattern.MatchString(term) {
+			continue
+		}
+		pullRequests, err := c.ListPullRequestsForCommit(ctx, term)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range pullRequests {
+			if !pr.Merged {
+				continue
+			}
+			numbers = append(numbers, pr.Number)
+		}
+	}
+	return uniqueInts(numbers), nil
+}
+
+func (c *GitLabClient) ListPullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	var response []mergeRequestDTO
+	if err := c.rest.request(
+		ctx,
+		http.MethodGet,
+		c.projectPath("repository/commits/"+url.PathEscape(sha)+"/merge_requests"),
+		nil,
+		nil,
+		&response,
+	); err != nil {
+		return nil, err
+	}
+
+	pullRequests := make([]PullRequest, 0, len(response))
+	for _, mr := range response {
+		pullRequests = append(pullRequests, mr.toDomain())
+	}
+	return pullRequests, nil
+}
+
+func (c *GitLabClient) getMergeRequest(ctx context.Context, number int) (*mergeRequestDTO, error) {
+	var response mergeRequestDTO
+	if err := c.rest.request(ctx, http.MethodGet, c.mergeRequestPath(number), nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *GitLabClient) lookupUserIDs(ctx context.Context, usernames []string) ([]int, error) {
+	userIDs := make([]int, 0, len(usernames))
+	for _, username := range uniqueStrings(usernames) {
+		query := url.Values{}
+		query.Set("username", username)
+
+		var response []gitLabUserDTO
+		if err := c.rest.request(ctx, http.MethodGet, "users", query, nil, &response); err != nil {
+			return nil, err
+		}
+		if len(response) == 0 {
+			return nil, fmt.Errorf("gitlab user %q not found", username)
+		}
+		userIDs = append(userIDs, response[0].ID)
+	}
+	return userIDs, nil
+}
+
+func (c *GitLabClient) projectPath(suffix string) string {
+	return "projects/" + url.PathEscape(c.repository.FullName()) + "/" + suffix
+}
+
+func (c *GitLabClient) mergeRequestPath(number int) string {
+	return c.projectPath(fmt.Sprintf("merge_requests/%d", number))
+}
+
+func mergeUserIDs(current []gitLabUserDTO, added []int) []int {
+	ids := make([]int, 0, len(current)+len(added))
+	for _, user := range current {
+		ids = append(ids, user.ID)
+	}
+	ids = append(ids, added...)
+	return uniqueInts(ids)
+}
+
+type gitLabUserDTO struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	WebURL    string `json:"web_url"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+func (u gitLabUserDTO) toDomain() User {
+	return User{
+		LoginName: u.Username,
+		URL:       u.WebURL,
+		Avatar:    u.AvatarURL,
+	}
+}
+
+type mergeRequestDTO struct {
+	IID             int             `json:"iid"`
+	Title           string          `json:"title"`
+	Description     string          `json:"description"`
+	WebURL          string          `json:"web_url"`
+	State           string          `json:"state"`
+	MergeCommitSHA  string          `json:"merge_commit_sha"`
+	SquashCommitSHA string          `json:"squash_commit_sha"`
+	SourceBranch    string          `json:"source_branch"`
+	TargetBranch    string          `json:"target_branch"`
+	MergedAt        *time.Time      `json:"merged_at"`
+	Author          gitLabUserDTO   `json:"author"`
+	Assignee        *gitLabUserDTO  `json:"assignee"`
+	Assignees       []gitLabUserDTO `json:"assignees"`
+	Reviewers       []gitLabUserDTO `json:"reviewers"`
+	Labels          []string        `json:"labels"`
+	Milestone       *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+}
+
+func (mr mergeRequestDTO) toDomain() PullRequest {
+	domain := PullRequest{
+		Number:         mr.IID,
+		Title:          mr.Title,
+		Body:           mr.Description,
+		URL:            mr.WebURL,
+		State:          mr.State,
+		Merged:         mr.State == "merged",
+		MergeCommitSHA: mr.MergeCommitSHA,
+		HeadRef:        mr.SourceBranch,
+		BaseRef:        mr.TargetBranch,
+		User:           mr.Author.toDomain(),
+		Assignees:      make([]User, 0, len(mr.Assignees)),
+		Labels:         mr.Labels,
+	}
+	switch mr.State {
+	case "opened":
+		domain.State = "open"
+	case "merged":
+		domain.State = "closed"
+	}
+	if domain.MergeCommitSHA == "" {
+		domain.MergeCommitSHA = mr.SquashCommitSHA
+	}
+	if mr.MergedAt != nil {
+		domain.MergedAt = *mr.MergedAt
+	}
+	if mr.Assignee != nil {
+		assignee := mr.Assignee.toDomain()
+		domain.Assignee = &assignee
+	}
+	for _, assignee := range mr.Assignees {
+		domain.Assignees = append(domain.Assignees, assignee.toDomain())
+	}
+	if mr.Milestone != nil {
+		domain.Milestone = mr.Milestone.Title
+	}
+	return domain
+}
+
+type mergeRequestDiffDTO struct {
+	OldPath     string `json:"old_path"`
+	NewPath     string `json:"new_path"`
+	NewFile     bool   `json:"new_file"`
+	RenamedFile bool   `json:"renamed_file"`
+	DeletedFile bool   `json:"deleted_file"`
+	Diff        string `json:"diff"`
+}
+
+func (d mergeRequestDiffDTO) toDomain() ChangedFile {
+	file := ChangedFile{
+		Filename: d.NewPath,
+		Status:   "modified",
+		Patch:    d.Diff,
+	}
+	switch {
+	case d.NewFile:
+		file.Status = "added"
+	case d.DeletedFile:
+		file.Status = "removed"
+		file.Filename = d.OldPath
+	case d.RenamedFile:
+		file.Status = "renamed"
+	}
+	for _, line := range splitLines(d.Diff) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		case strings.HasPrefix(line, "+"):
+			file.Additions++
+		case strings.HasPrefix(line, "-"):
+			file.Deletions++
+		}
+	}
+	file.Changes = file.Additions + file.Deletions
+	return file
+}
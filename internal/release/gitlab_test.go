@@ -0,0 +1,138 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGitLabClientGetPullRequestsUsesIIDFilter(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Fsub%2Fexample/merge_requests" {
+			http.NotFound(w, r)
+			return
+		}
+
+		requests++
+		var response []mergeRequestDTO
+		for _, raw := range r.URL.Query()["iids[]"] {
+			var iid int
+			_ = json.Unmarshal([]byte(raw), &iid)
+			if iid > 150 {
+				continue
+			}
+			response = append(response, mergeRequestDTO{
+				IID:             iid,
+				State:           "merged",
+				SquashCommitSHA: "squash",
+				Author:          gitLabUserDTO{Username: "alice"},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitLabClient(server)
+
+	pullRequests, err := client.GetPullRequests(context.Background(), sequence(1, 160))
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), sequence(1, 150); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+	if got := pullRequests[0]; !got.Merged || got.State != "closed" || got.MergeCommitSHA != "squash" || got.User.LoginName != "alice" {
+		t.Fatalf("unexpected pull request: %+v", got)
+	}
+}
+
+func TestGitLabClientAddAssigneesKeepsExistingAssignees(t *testing.T) {
+	t.Parallel()
+
+	var updated map[string][]int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/v4/users":
+			ids := map[string]int{"alice": 10, "bob": 20}
+			_ = json.NewEncoder(w).Encode([]gitLabUserDTO{{ID: ids[r.URL.Query().Get("username")]}})
+		case r.URL.Path == "/api/v4/projects/group/sub/example/merge_requests/5" && r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(mergeRequestDTO{IID: 5, Assignees: []gitLabUserDTO{{ID: 20}, {ID: 30}}})
+		case r.URL.Path == "/api/v4/projects/group/sub/example/merge_requests/5" && r.Method == http.MethodPut:
+			_ = json.NewDecoder(r.Body).Decode(&updated)
+			_ = json.NewEncoder(w).Encode(mergeRequestDTO{IID: 5})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitLabClient(server)
+
+	if err := client.AddAssignees(context.Background(), 5, []string{"alice", "bob"}); err != nil {
+		t.Fatalf("add assignees: %v", err)
+	}
+
+	if got, want := updated["assignee_ids"], []int{20, 30, 10}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGitLabClientListPullRequestFiles(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/projects/group/sub/example/merge_requests/5/diffs" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]mergeRequestDiffDTO{
+			{OldPath: "README.md", NewPath: "README.md", Diff: "@@ -1 +1,2 @@\n-base\n+base\n+feature\n"},
+			{OldPath: "old.txt", NewPath: "old.txt", DeletedFile: true, Diff: "@@ -1 +0,0 @@\n-old\n"},
+			{OldPath: "new.txt", NewPath: "new.txt", NewFile: true},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitLabClient(server)
+
+	files, err := client.ListPullRequestFiles(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("list pull request files: %v", err)
+	}
+
+	want := []ChangedFile{
+		{Filename: "README.md", Status: "modified", Additions: 2, Deletions: 1, Changes: 3, Patch: "@@ -1 +1,2 @@\n-base\n+base\n+feature\n"},
+		{Filename: "old.txt", Status: "removed", Deletions: 1, Changes: 1, Patch: "@@ -1 +0,0 @@\n-old\n"},
+		{Filename: "new.txt", Status: "added"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got %+v, want %+v", files, want)
+	}
+}
+
+func newTestGitLabClient(server *httptest.Server) *GitLabClient {
+	parsedURL, err := url.Parse(server.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	repository := Repository{Host: parsedURL.Host, Scheme: parsedURL.Scheme, Owner: "group/sub", Name: "example", Provider: ProviderGitLab}
+	rest := newTestGitHubClient(server)
+	rest.baseURL = server.URL + "/api/v4/"
+	rest.repository = repository
+	return &GitLabClient{
+		rest:       rest,
+		repository: repository,
+	}
+}
@@ -0,0 +1,564 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	gogit "github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+type GoGit struct {
+	Dir string
+
+	// go-git reads neither credential helpers nor http.extraheader, so
+	// HTTPS remotes are authenticated with the API token.
+	tokens tokenSource
+
+	mu   sync.Mutex
+	repo *gogit.Repository
+}
+
+func NewGoGit(dir string) *GoGit {
+	if dir == "" {
+		dir = "."
+	}
+	return &GoGit{Dir: dir}
+}
+
+func (g *GoGit) open() (*gogit.Repository, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.repo != nil {
+		return g.repo, nil
+	}
+	repo, err := gogit.PlainOpenWithOptions(g.Dir, &gogit.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open git repository %s: %w", g.Dir, err)
+	}
+	g.repo = repo
+	return repo, nil
+}
+
+func (g *GoGit) Root(ctx context.Context) (string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("resolve git worktree: %w", err)
+	}
+	return worktree.Filesystem.Root(), nil
+}
+
+func (g *GoGit) ResolveRemote(ctx context.Context, remoteName string) (Repository, error) {
+	return resolveRemote(ctx, g, remoteName)
+}
+
+func (g *GoGit) LookupProjectConfig(ctx context.Context, repo Repository, key string) (string, bool, error) {
+	root, err := g.Root(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	projectConfigPath := filepath.Join(root, ".git-pr-release")
+	if file, err := os.Open(projectConfigPath); err == nil {
+		projectConfig := config.New()
+		decodeErr := config.NewDecoder(file).Decode(projectConfig)
+		file.Close()
+		if decodeErr != nil {
+			return "", false, fmt.Errorf("parse %s: %w", projectConfigPath, decodeErr)
+		}
+		if value, ok := lookupRawConfig(projectConfig, "pr-release."+key); ok {
+			return value, true, nil
+		}
+	}
+
+	hostAwareKey := "pr-release." + key
+	if repo.Host != "" {
+		hostAwareKey = "pr-release." + repo.Host + "." + key
+	}
+	return g.LookupConfig(ctx, hostAwareKey)
+}
+
+// ProjectConfigKeys lists the pr-release.* keys set in .git-pr-release with
+// the section and variable names lowercased, as git prints them.
+func (g *GoGit) ProjectConfigKeys(ctx context.Context) ([]string, error) {
+	root, err := g.Root(ctx)
+	if err != nil {
+		return nil, err
+	}
+	projectConfigPath := filepath.Join(root, ".git-pr-release")
+	file, err := os.Open(projectConfigPath)
+	if err != nil {
+		return nil, nil
+	}
+	defer file.Close()
+	projectConfig := config.New()
+	if err := config.NewDecoder(file).Decode(projectConfig); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", projectConfigPath, err)
+	}
+
+	var keys []string
+	for _, section := range projectConfig.Sections {
+		if !section.IsName("pr-release") {
+			continue
+		}
+		for _, option := range section.Options {
+			keys = append(keys, "pr-release."+strings.ToLower(option.Key))
+		}
+		for _, subsection := range section.Subsections {
+			for _, option := range subsection.Options {
+				keys = append(keys, "pr-release."+subsection.Name+"."+strings.ToLower(option.Key))
+			}
+		}
+	}
+	return keys, nil
+}
+
+// LookupConfig resolves key from the repository, global and system config in
+// that order, mirroring `git config <key>`.
+func (g *GoGit) LookupConfig(ctx context.Context, key string) (string, bool, error) {
+	repo, err := g.open()
+	if err != nil {
+		return "", false, err
+	}
+	local, err := repo.Config()
+	if err != nil {
+		return "", false, fmt.Errorf("read git config: %w", err)
+	}
+	if value, ok := lookupRawConfig(local.Raw, key); ok {
+		return value, true, nil
+	}
+
+	for _, scope := range []gitconfig.Scope{gitconfig.GlobalScope, gitconfig.SystemScope} {
+		scoped, err := gitconfig.LoadConfig(scope)
+		if err != nil {
+			continue
+		}
+		if value, ok := lookupRawConfig(scoped.Raw, key); ok {
+			return value, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (g *GoGit) IsShallow(ctx context.Context) (bool, error) {
+	repo, err := g.open()
+	if err != nil {
+		return false, err
+	}
+	shallow, err := repo.Storer.Shallow()
+	if err != nil {
+		return false, fmt.Errorf("read shallow commits: %w", err)
+	}
+	return len(shallow) > 0, nil
+}
+
+func (g *GoGit) Unshallow(ctx context.Context) error {
+	return errors.New("the go-git backend cannot unshallow a repository; fetch the full history (e.g. fetch-depth: 0) or use --git-backend exec")
+}
+
+func (g *GoGit) RemoteUpdate(ctx context.Context, remoteName string) error {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	repo, err := g.open()
+	if err != nil {
+		return err
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return fmt.Errorf("git remote %q: %w", remoteName, err)
+	}
+	auth, err := g.auth(ctx, remote)
+	if err != nil {
+		return err
+	}
+	err = remote.FetchContext(ctx, &gogit.FetchOptions{RemoteName: remoteName, Auth: auth})
+	if err != nil && !errors.Is(err, gogit.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("fetch %s: %w", remoteName, err)
+	}
+	return nil
+}
+
+// auth returns nil for SSH and local remotes, which go-git authenticates on
+// its own.
+func (g *GoGit) auth(ctx context.Context, remote *gogit.Remote) (transport.AuthMethod, error) {
+	urls := remote.Config().URLs
+	if g.tokens == nil || len(urls) == 0 {
+		return nil, nil
+	}
+	if !strings.HasPrefix(urls[0], "https://") && !strings.HasPrefix(urls[0], "http://") {
+		return nil, nil
+	}
+	token, err := g.tokens.Token(ctx)
+	if err != nil || token == "" {
+		return nil, err
+	}
+	return &githttp.BasicAuth{Username: "x-access-token", Password: token}, nil
+}
+
+func (g *GoGit) MergedPRNumbers(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]int, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	repo, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := g.resolveRange(repo, remoteName, productionBranch, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+	merges, err := commits.merges(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	featureShas := make(map[plumbing.Hash]struct{}, len(merges))
+	for _, merge := range merges {
+		featureShas[merge.ParentHashes[1]] = struct{}{}
+	}
+
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return nil, fmt.Errorf("git remote %q: %w", remoteName, err)
+	}
+	auth, err := g.auth(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := remote.ListContext(ctx, &gogit.ListOptions{Auth: auth})
+	if err != nil {
+		return nil, fmt.Errorf("list refs of %s: %w", remoteName, err)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Name() < refs[j].Name()
+	})
+
+	var numbers []int
+	seen := map[int]struct{}{}
+	for _, ref := range refs {
+		if _, ok := featureShas[ref.Hash()]; !ok {
+			continue
+		}
+		number, ok := parsePullRequestRef(ref.Name().String())
+		if !ok {
+			continue
+		}
+		// A head already reachable from production is its own merge base,
+		// which the exec backend detects with one `git merge-base` per ref.
+		if _, released := commits.excluded[ref.Hash()]; released {
+			continue
+		}
+		if _, exists := seen[number]; exists {
+			continue
+		}
+		seen[number] = struct{}{}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func (g *GoGit) SquashCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error) {
+	shas, err := g.FirstParentCommitSHAs(ctx, remoteName, productionBranch, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+	for idx, sha := range shas {
+		shas[idx] = shortSHA(sha)
+	}
+	return shas, nil
+}
+
+func (g *GoGit) MergeCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	repo, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := g.resolveRange(repo, remoteName, productionBranch, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+	merges, err := commits.merges(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	shas := make([]string, 0, len(merges))
+	for _, merge := range merges {
+		shas = append(shas, merge.Hash.String())
+	}
+	return shas, nil
+}
+
+func (g *GoGit) FirstParentCommitSHAs(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]string, error) {
+	commits, err := g.FirstParentCommits(ctx, remoteName, productionBranch, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	var shas []string
+	for _, commit := range commits {
+		if !commit.Merge {
+			shas = append(shas, commit.SHA)
+		}
+	}
+	return shas, nil
+}
+
+func (g *GoGit) FirstParentCommits(ctx context.Context, remoteName, productionBranch, stagingBranch string) ([]Commit, error) {
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	repo, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	commits, err := g.resolveRange(repo, remoteName, productionBranch, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Commit
+	commit := commits.tip
+	for commit != nil {
+		if _, ok := commits.excluded[commit.Hash]; ok {
+			break
+		}
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		result = append(result, Commit{
+			SHA:     commit.Hash.String(),
+			Subject: strings.TrimSpace(subject),
+			Merge:   commit.NumParents() > 1,
+		})
+		if commit.NumParents() == 0 {
+			break
+		}
+		commit, err = loadCommit(repo, commit.ParentHashes[0])
+		if err != nil {
+			if errors.Is(err, plumbing.ErrObjectNotFound) {
+				break
+			}
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (g *GoGit) ResolveRevision(ctx context.Context, revision string) (string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return "", err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", revision, err)
+	}
+	return hash.String(), nil
+}
+
+func (g *GoGit) MergedTags(ctx context.Context, revision string) ([]string, error) {
+	repo, err := g.open()
+	if err != nil {
+		return nil, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", revision, err)
+	}
+	tip, err := loadCommit(repo, *hash)
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := map[plumbing.Hash]struct{}{}
+	if err := walkCommits(repo, tip, func(commit *object.Commit) bool {
+		if _, ok := reachable[commit.Hash]; ok {
+			return false
+		}
+		reachable[commit.Hash] = struct{}{}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	var tags []string
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		target := ref.Hash()
+		if tag, err := repo.TagObject(target); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				// Tags of trees or blobs cannot be merged into a branch.
+				return nil
+			}
+			target = commit.Hash
+		}
+		if _, ok := reachable[target]; ok {
+			tags = append(tags, ref.Name().Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// commitRange is the in-process equivalent of `production..staging`.
+type commitRange struct {
+	tip      *object.Commit
+	excluded map[plumbing.Hash]struct{}
+}
+
+func (g *GoGit) resolveRange(repo *gogit.Repository, remoteName, productionBranch, stagingBranch string) (*commitRange, error) {
+	production, err := resolveRemoteBranch(repo, remoteName, productionBranch)
+	if err != nil {
+		return nil, err
+	}
+	staging, err := resolveRemoteBranch(repo, remoteName, stagingBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[plumbing.Hash]struct{}{}
+	if err := walkCommits(repo, production, func(commit *object.Commit) bool {
+		if _, ok := excluded[commit.Hash]; ok {
+			return false
+		}
+		excluded[commit.Hash] = struct{}{}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	return &commitRange{tip: staging, excluded: excluded}, nil
+}
+
+// merges returns the merge commits of the range, newest first like `git log`.
+func (r *commitRange) merges(repo *gogit.Repository) ([]*object.Commit, error) {
+	visited := map[plumbing.Hash]struct{}{}
+	var merges []*object.Commit
+	if err := walkCommits(repo, r.tip, func(commit *object.Commit) bool {
+		if _, ok := r.excluded[commit.Hash]; ok {
+			return false
+		}
+		if _, ok := visited[commit.Hash]; ok {
+			return false
+		}
+		visited[commit.Hash] = struct{}{}
+		if commit.NumParents() > 1 {
+			merges = append(merges, commit)
+		}
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(merges, func(i, j int) bool {
+		return merges[i].Committer.When.After(merges[j].Committer.When)
+	})
+	return merges, nil
+}
+
+func resolveRemoteBranch(repo *gogit.Repository, remoteName, branch string) (*object.Commit, error) {
+	ref, err := repo.Reference(plumbing.NewRemoteReferenceName(remoteName, branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s/%s: %w", remoteName, branch, err)
+	}
+	return loadCommit(repo, ref.Hash())
+}
+
+// walkCommits visits commits reachable from start. Returning false from visit
+// stops the walk from descending into that commit's parents. Parents missing
+// from a shallow clone are treated as the end of history.
+func walkCommits(repo *gogit.Repository, start *object.Commit, visit func(*object.Commit) bool) error {
+	queue := []*object.Commit{start}
+	for len(queue) > 0 {
+		commit := queue[0]
+		queue = queue[1:]
+		if !visit(commit) {
+			continue
+		}
+		for _, parentHash := range commit.ParentHashes {
+			parent, err := loadCommit(repo, parentHash)
+			if err != nil {
+				if errors.Is(err, plumbing.ErrObjectNotFound) {
+					continue
+				}
+				return err
+			}
+			queue = append(queue, parent)
+		}
+	}
+	return nil
+}
+
+func loadCommit(repo *gogit.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", hash, err)
+	}
+	return commit, nil
+}
+
+// lookupRawConfig splits key the way git does: the first dot ends the section
+// and the last dot starts the variable name; anything between is the
+// (case-sensitive) subsection.
+func lookupRawConfig(cfg *config.Config, key string) (string, bool) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", false
+	}
+	sectionName := key[:first]
+	name := key[last+1:]
+	subsectionName := ""
+	if first != last {
+		subsectionName = key[first+1 : last]
+	}
+
+	value, found := "", false
+	for _, section := range cfg.Sections {
+		if !section.IsName(sectionName) {
+			continue
+		}
+		if subsectionName == "" {
+			if section.Options.Has(name) {
+				value, found = section.Options.Get(name), true
+			}
+			continue
+		}
+		for _, subsection := range section.Subsections {
+			if subsection.IsName(subsectionName) && subsection.Options.Has(name) {
+				value, found = subsection.Options.Get(name), true
+			}
+		}
+	}
+	return strings.TrimSpace(value), found
+}
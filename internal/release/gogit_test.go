@@ -0,0 +1,236 @@
+package release
+
+import (
+	"context"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGoGitMatchesExecBackend(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		setup func(*testing.T) string
+	}{
+		{name: "merged pull requests", setup: setupRepositoryWithMergedPullRequests},
+		{name: "chained and unrelated pull requests", setup: setupRepositoryWithChainedAndUnrelatedPullRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			workDir := tt.setup(t)
+			ctx := context.Background()
+			execGit := NewGit(workDir)
+			goGit := NewGoGit(workDir)
+
+			if err := goGit.RemoteUpdate(ctx, DefaultRemoteName); err != nil {
+				t.Fatalf("remote update: %v", err)
+			}
+
+			wantRoot, err := execGit.Root(ctx)
+			if err != nil {
+				t.Fatalf("exec root: %v", err)
+			}
+			gotRoot, err := goGit.Root(ctx)
+			if err != nil {
+				t.Fatalf("go-git root: %v", err)
+			}
+			if evalSymlinks(t, gotRoot) != evalSymlinks(t, wantRoot) {
+				t.Fatalf("root: got %q, want %q", gotRoot, wantRoot)
+			}
+
+			shallow, err := goGit.IsShallow(ctx)
+			if err != nil || shallow {
+				t.Fatalf("is shallow: %v (err=%v)", shallow, err)
+			}
+
+			wantNumbers, err := execGit.MergedPRNumbers(ctx, DefaultRemoteName, "master", "staging")
+			if err != nil {
+				t.Fatalf("exec merged pr numbers: %v", err)
+			}
+			gotNumbers, err := goGit.MergedPRNumbers(ctx, DefaultRemoteName, "master", "staging")
+			if err != nil {
+				t.Fatalf("go-git merged pr numbers: %v", err)
+			}
+			if len(wantNumbers) == 0 {
+				t.Fatalf("fixture has no merged pull requests")
+			}
+			sort.Ints(wantNumbers)
+			sort.Ints(gotNumbers)
+			if !reflect.DeepEqual(gotNumbers, wantNumbers) {
+				t.Fatalf("merged pr numbers: got %v, want %v", gotNumbers, wantNumbers)
+			}
+
+			for name, list := range map[string]func(GitBackend) ([]string, error){
+				"merge commits": func(g GitBackend) ([]string, error) {
+					return g.MergeCommitSHAs(ctx, DefaultRemoteName, "master", "staging")
+				},
+				"first parent commits": func(g GitBackend) ([]string, error) {
+					return g.FirstParentCommitSHAs(ctx, DefaultRemoteName, "master", "staging")
+				},
+				"squash commits": func(g GitBackend) ([]string, error) {
+					return g.SquashCommitSHAs(ctx, DefaultRemoteName, "master", "staging")
+				},
+			} {
+				want, err := list(execGit)
+				if err != nil {
+					t.Fatalf("exec %s: %v", name, err)
+				}
+				got, err := list(goGit)
+				if err != nil {
+					t.Fatalf("go-git %s: %v", name, err)
+				}
+				sort.Strings(want)
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: got %v, want %v", name, got, want)
+				}
+			}
+
+			wantCommits, err := execGit.FirstParentCommits(ctx, DefaultRemoteName, "master", "staging")
+			if err != nil {
+				t.Fatalf("exec first parent commits: %v", err)
+			}
+			gotCommits, err := goGit.FirstParentCommits(ctx, DefaultRemoteName, "master", "staging")
+			if err != nil {
+				t.Fatalf("go-git first parent commits: %v", err)
+			}
+			if !reflect.DeepEqual(gotCommits, wantCommits) {
+				t.Fatalf("first parent commits: got %v, want %v", gotCommits, wantCommits)
+			}
+
+			wantSHA, err := execGit.ResolveRevision(ctx, "origin/staging")
+			if err != nil {
+				t.Fatalf("exec resolve revision: %v", err)
+			}
+			gotSHA, err := goGit.ResolveRevision(ctx, "origin/staging")
+			if err != nil || gotSHA != wantSHA {
+				t.Fatalf("resolve revision: got %q (err=%v), want %q", gotSHA, err, wantSHA)
+			}
+			if _, err := goGit.ResolveRevision(ctx, "origin/missing"); err == nil {
+				t.Fatalf("expected missing revision to fail")
+			}
+		})
+	}
+}
+
+func TestGoGitLookupProjectConfig(t *testing.T) {
+	t.Parallel()
+
+	workDir := t.TempDir()
+	runGit(t, workDir, "init")
+	runGit(t, workDir, "remote", "add", "origin", "ssh://git@ghe.example.com/octo/example.git")
+	runGit(t, workDir, "config", "pr-release.ghe.example.com.branch.staging", "integration")
+	configPath := filepath.Join(workDir, ".git-pr-release")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.branch.production", "production")
+
+	git := NewGoGit(workDir)
+	repo, err := git.ResolveRemote(context.Background(), DefaultRemoteName)
+	if err != nil {
+		t.Fatalf("resolve remote: %v", err)
+	}
+	if repo.Host != "ghe.example.com" || repo.FullName() != "octo/example" {
+		t.Fatalf("unexpected repository: %+v", repo)
+	}
+
+	production, ok, err := git.LookupProjectConfig(context.Background(), repo, "branch.production")
+	if err != nil {
+		t.Fatalf("lookup project config: %v", err)
+	}
+	if !ok || production != "production" {
+		t.Fatalf("unexpected production branch config: %q (ok=%v)", production, ok)
+	}
+
+	staging, ok, err := git.LookupProjectConfig(context.Background(), repo, "branch.staging")
+	if err != nil {
+		t.Fatalf("lookup host-aware config: %v", err)
+	}
+	if !ok || staging != "integration" {
+		t.Fatalf("unexpected staging branch config: %q (ok=%v)", staging, ok)
+	}
+
+	if _, ok, err := git.LookupProjectConfig(context.Background(), repo, "template"); err != nil || ok {
+		t.Fatalf("expected missing key, got ok=%v err=%v", ok, err)
+	}
+
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.Hotfix.labels", "hotfix")
+	runGit(t, workDir, "config", "-f", configPath, "pr-release.hotfix.branch.staging", "hotfix")
+	wantKeys, err := NewGit(workDir).ProjectConfigKeys(context.Background())
+	if err != nil {
+		t.Fatalf("exec project config keys: %v", err)
+	}
+	gotKeys, err := git.ProjectConfigKeys(context.Background())
+	if err != nil {
+		t.Fatalf("go-git project config keys: %v", err)
+	}
+	sort.Strings(wantKeys)
+	sort.Strings(gotKeys)
+	if len(wantKeys) != 3 || !reflect.DeepEqual(gotKeys, wantKeys) {
+		t.Fatalf("project config keys: got %v, want %v", gotKeys, wantKeys)
+	}
+}
+
+func evalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatalf("eval symlinks %s: %v", path, err)
+	}
+	return resolved
+}
+
+func TestGoGitAuthenticatesHTTPSRemotesWithToken(t *testing.T) {
+	t.Parallel()
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	originDir := filepath.Join(filepath.Dir(workDir), "origin.git")
+
+	backend := &cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + filepath.Dir(originDir), "GIT_HTTP_EXPORT_ALL=1"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "x-access-token" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		backend.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	runGit(t, workDir, "remote", "set-url", "origin", server.URL+"/origin.git")
+
+	ctx := context.Background()
+	anonymous := NewGoGit(workDir)
+	if err := anonymous.RemoteUpdate(ctx, DefaultRemoteName); err == nil {
+		t.Fatalf("expected the fetch without a token to be rejected")
+	}
+
+	goGit := NewGitBackend(Config{WorkDir: workDir, GitBackend: GitBackendGoGit, Token: "secret"})
+	if err := goGit.RemoteUpdate(ctx, DefaultRemoteName); err != nil {
+		t.Fatalf("remote update: %v", err)
+	}
+	numbers, err := goGit.MergedPRNumbers(ctx, DefaultRemoteName, "master", "staging")
+	if err != nil {
+		t.Fatalf("merged pr numbers: %v", err)
+	}
+	if len(numbers) == 0 {
+		t.Fatalf("expected merged pull requests from the authenticated remote")
+	}
+}
@@ -0,0 +1,728 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const graphQLPullRequestBatchSize = 50
+
+const graphQLPullRequestFields = `fragment PullRequestFields on PullRequest {
+  number
+  title
+  body
+  url
+  state
+  merged
+  mergedAt
+  headRefName
+  baseRefName
+  mergeCommit { oid }
+  author { login url avatarUrl }
+  assignees(first: 20) { nodes { login url avatarUrl } }
+  labels(first: 50) { nodes { name } }
+  milestone { title }
+}`
+
+type GraphQLGitHubClient struct {
+	rest       *RESTGitHubClient
+	endpoint   string
+	repository Repository
+
+	mu           sync.Mutex
+	repositoryID string
+}
+
+func NewGraphQLGitHubClient(config Config) *GraphQLGitHubClient {
+	return &GraphQLGitHubClient{
+		rest:       NewRESTGitHubClient(config),
+		endpoint:   config.Repository.GraphQLURL(),
+		repository: config.Repository,
+	}
+}
+
+func (c *GraphQLGitHubClient) Token(ctx context.Context) (string, error) {
+	return c.rest.Token(ctx)
+}
+
+func (c *GraphQLGitHubClient) CacheStats() CacheStats {
+	return c.rest.CacheStats()
+}
+
+func (c *GraphQLGitHubClient) GetPullRequests(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	numbers = uniqueInts(numbers)
+	if len(numbers) == 0 {
+		return nil, nil
+	}
+
+	found := make(map[int]PullRequest, len(numbers))
+	for start := 0; start < len(numbers); start += graphQLPullRequestBatchSize {
+		end := min(start+graphQLPullRequestBatchSize, len(numbers))
+		batch := numbers[start:end]
+
+		declarations := []string{"$owner: String!", "$name: String!"}
+		fields := make([]string, 0, len(batch))
+		variables := c.repositoryVariables()
+		for _, number := range batch {
+			declarations = append(declarations, fmt.Sprintf("$n%d: Int!", number))
+			fields = append(fields, fmt.Sprintf("pr%d: pullRequest(number: $n%d) { ...PullRequestFields }", number, number))
+			variables[fmt.Sprintf("n%d", number)] = number
+		}
+		query := fmt.Sprintf(
+			"query(%s) {\n  repository(owner: $owner, name: $name) {\n    %s\n  }\n}\n%s",
+			strings.Join(declarations, ", "),
+			strings.Join(fields, "\n    "),
+			graphQLPullRequestFields,
+		)
+
+		var response struct {
+			Repository map[string]*graphQLPullRequest `json:"repository"`
+		}
+		if err := c.query(ctx, query, variables, &response, true); err != nil {
+			return nil, err
+		}
+		for _, pr := range response.Repository {
+			if pr == nil {
+				continue
+			}
+			found[pr.Number] = pr.toDomain()
+		}
+	}
+
+	pullRequests := make([]PullRequest, 0, len(found))
+	for _, number := range numbers {
+		if pr, ok := found[number]; ok {
+			pullRequests = append(pullRequests, pr)
+		}
+	}
+	return pullRequests, nil
+}
+
+func (c *GraphQLGitHubClient) ListOpenReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error) {
+	owner, branch := c.repository.Owner, head
+	if idx := strings.Index(head, ":"); idx >= 0 {
+		owner, branch = head[:idx], head[idx+1:]
+	}
+
+	query := `query($owner: String!, $name: String!, $head: String!, $base: String!) {
+  repository(owner: $owner, name: $name) {
+    pullRequests(states: OPEN, headRefName: $head, baseRefName: $base, first: 100) {
+      nodes { ...PullRequestFields headRepositoryOwner { login } }
+    }
+  }
+}
+` + graphQLPullRequestFields
+	variables := c.repositoryVariables()
+	variables["head"] = branch
+	variables["base"] = base
+
+	var response struct {
+		Repository struct {
+			PullRequests struct {
+				Nodes []struct {
+					graphQLPullRequest
+					HeadRepositoryOwner *struct {
+						Login string `json:"login"`
+					} `json:"headRepositoryOwner"`
+				} `json:"nodes"`
+			} `json:"pullRequests"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &response, false); err != nil {
+		return nil, err
+	}
+
+	pullRequests := make([]PullRequest, 0, len(response.Repository.PullRequests.Nodes))
+	for _, node := range response.Repository.PullRequests.Nodes {
+		if owner != "" && node.HeadRepositoryOwner != nil && !strings.EqualFold(node.HeadRepositoryOwner.Login, owner) {
+			continue
+		}
+		pullRequests = append(pullRequests, node.toDomain())
+	}
+	return pullRequests, nil
+}
+
+func (c *GraphQLGitHubClient) CreatePullRequest(ctx context.Context, title, head, base, body string) (*PullRequest, error) {
+	repositoryID, err := c.lookupRepositoryID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `mutation($input: CreatePullRequestInput!) {
+  createPullRequest(input: $input) {
+    pullRequest { ...PullRequestFields }
+  }
+}
+` + graphQLPullRequestFields
+	variables := map[string]any{
+		"input": map[string]string{
+			"repositoryId": repositoryID,
+			"headRefName":  head,
+			"baseRefName":  base,
+			"title":        title,
+			"body":         body,
+		},
+	}
+
+	var response struct {
+		CreatePullRequest struct {
+			PullRequest graphQLPullRequest `json:"pullRequest"`
+		} `json:"createPullRequest"`
+	}
+	if err := c.query(ctx, query, variables, &response, false); err != nil {
+		return nil, err
+	}
+
+	pr := response.CreatePullRequest.PullRequest.toDomain()
+	return &pr, nil
+}
+
+func (c *GraphQLGitHubClient) UpdatePullRequest(ctx context.Context, number int, title, body string) (*PullRequest, error) {
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `mutation($input: UpdatePullRequestInput!) {
+  updatePullRequest(input: $input) {
+    pullRequest { ...PullRequestFields }
+  }
+}
+` + graphQLPullRequestFields
+	variables := map[string]any{
+		"input": map[string]string{
+			"pullRequestId": pullRequestID,
+			"title":         title,
+			"body":          body,
+		},
+	}
+
+	var response struct {
+		UpdatePullRequest struct {
+			PullRequest graphQLPullRequest `json:"pullRequest"`
+		} `json:"updatePullRequest"`
+	}
+	if err := c.query(withIdempotentRequest(ctx), query, variables, &response, false); err != nil {
+		return nil, err
+	}
+
+	pr := response.UpdatePullRequest.PullRequest.toDomain()
+	return &pr, nil
+}
+
+func (c *GraphQLGitHubClient) AddLabels(ctx context.Context, number int, labels []string) error {
+	labels = uniqueStrings(labels)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return err
+	}
+
+	declarations := []string{"$owner: String!", "$name: String!"}
+	fields := make([]string, 0, len(labels))
+	variables := c.repositoryVariables()
+	for idx, label := range labels {
+		declarations = append(declarations, fmt.Sprintf("$l%d: String!", idx))
+		fields = append(fields, fmt.Sprintf("l%d: label(name: $l%d) { id }", idx, idx))
+		variables[fmt.Sprintf("l%d", idx)] = label
+	}
+	query := fmt.Sprintf(
+		"query(%s) {\n  repository(owner: $owner, name: $name) {\n    %s\n  }\n}",
+		strings.Join(declarations, ", "),
+		strings.Join(fields, "\n    "),
+	)
+
+	var response struct {
+		Repository map[string]*graphQLNode `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &response, true); err != nil {
+		return err
+	}
+
+	labelIDs := make([]string, 0, len(labels))
+	var missing []string
+	for idx, label := range labels {
+		node := response.Repository[fmt.Sprintf("l%d", idx)]
+		if node == nil || node.ID == "" {
+			missing = append(missing, label)
+			continue
+		}
+		labelIDs = append(labelIDs, node.ID)
+	}
+
+	if len(labelIDs) > 0 {
+		mutation := `mutation($input: AddLabelsToLabelableInput!) {
+  addLabelsToLabelable(input: $input) { clientMutationId }
+}`
+		if err := c.query(withIdempotentRequest(ctx), mutation, map[string]any{
+			"input": map[string]any{
+				"labelableId": pullRequestID,
+				"labelIds":    labelIDs,
+			},
+		}, nil, false); err != nil {
+			return err
+		}
+	}
+
+	// GraphQL can only apply existing labels; the REST endpoint creates
+	// missing ones like the REST backend does.
+	if len(missing) > 0 {
+		return c.rest.AddLabels(ctx, number, missing)
+	}
+	return nil
+}
+
+func (c *GraphQLGitHubClient) AddAssignees(ctx context.Context, number int, assignees []string) error {
+	assignees = uniqueStrings(assignees)
+	if len(assignees) == 0 {
+		return nil
+	}
+
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return err
+	}
+	userIDs, err := c.lookupUserIDs(ctx, assignees)
+	if err != nil {
+		return err
+	}
+
+	mutation := `mutation($input: AddAssigneesToAssignableInput!) {
+  addAssigneesToAssignable(input: $input) { clientMutationId }
+}`
+	return c.query(withIdempotentRequest(ctx), mutation, map[string]any{
+		"input": map[string]any{
+			"assignableId": pullRequestID,
+			"assigneeIds":  userIDs,
+		},
+	}, nil, false)
+}
+
+func (c *GraphQLGitHubClient) RequestReviewers(ctx context.Context, number int, reviewers []string) error {
+	reviewers = uniqueStrings(reviewers)
+	if len(reviewers) == 0 {
+		return nil
+	}
+
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return err
+	}
+	userIDs, err := c.lookupUserIDs(ctx, reviewers)
+	if err != nil {
+		return err
+	}
+
+	mutation := `mutation($input: RequestReviewsInput!) {
+  requestReviews(input: $input) { clientMutationId }
+}`
+	return c.query(withIdempotentRequest(ctx), mutation, map[string]any{
+		"input": map[string]any{
+			"pullRequestId": pullRequestID,
+			"userIds":       userIDs,
+			"union":         true,
+		},
+	}, nil, false)
+}
+
+func (c *GraphQLGitHubClient) ListPullRequestFiles(ctx context.Context, number int) ([]ChangedFile, error) {
+	query := `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      files(first: 100, after: $after) {
+        nodes { path additions deletions changeType }
+        pageInfo { hasNextPage endCursor }
+      }
+    }
+  }
+}`
+
+	var files []ChangedFile
+	var after *string
+	for {
+		variables := c.repositoryVariables()
+		variables["number"] = number
+		variables["after"] = after
+
+		var response struct {
+			Repository struct {
+				PullRequest *struct {
+					Files struct {
+						Nodes []struct {
+							Path       string `json:"path"`
+							Additions  int    `json:"additions"`
+							Deletions  int    `json:"deletions"`
+							ChangeType string `json:"changeType"`
+						} `json:"nodes"`
+						PageInfo graphQLPageInfo `json:"pageInfo"`
+					} `json:"files"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		}
+		if err := c.query(ctx, query, variables, &response, false); err != nil {
+			return nil, err
+		}
+		if response.Repository.PullRequest == nil {
+			return nil, fmt.Errorf("pull request #%d not found in %s", number, c.repository.FullName())
+		}
+
+		page := response.Repository.PullRequest.Files
+		for _, node := range page.Nodes {
+			files = append(files, ChangedFile{
+				Filename:  node.Path,
+				Status:    graphQLChangeTypeStatus(node.ChangeType),
+				Additions: node.Additions,
+				Deletions: node.Deletions,
+				Changes:   node.Additions + node.Deletions,
+			})
+		}
+
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		cursor := page.PageInfo.EndCursor
+		after = &cursor
+	}
+
+	return files, nil
+}
+
+func (c *GraphQLGitHubClient) SearchPullRequestNumbers(ctx context.Context, query string) ([]int, error) {
+	searchQuery := `query($q: String!, $after: String) {
+  search(query: $q, type: ISSUE, first: 100, after: $after) {
+    nodes { ... on PullRequest { number } }
+    pageInfo { hasNextPage endCursor }
+  }
+}`
+
+	var numbers []int
+	var after *string
+	for {
+		var response struct {
+			Search struct {
+				Nodes []struct {
+					Number int `json:"number"`
+				} `json:"nodes"`
+				PageInfo graphQLPageInfo `json:"pageInfo"`
+			} `json:"search"`
+		}
+		if err := c.query(ctx, searchQuery, map[string]any{"q": query, "after": after}, &response, false); err != nil {
+			return nil, err
+		}
+
+		for _, node := range response.Search.Nodes {
+			if node.Number == 0 {
+				continue
+			}
+			numbers = append(numbers, node.Number)
+		}
+
+		if !response.Search.PageInfo.HasNextPage {
+			break
+		}
+		cursor := response.Search.PageInfo.EndCursor
+		after = &cursor
+	}
+
+	return uniqueInts(numbers), nil
+}
+
+func (c *GraphQLGitHubClient) ListPullRequestsForCommit(ctx context.Context, sha string) ([]PullRequest, error) {
+	query := `query($owner: String!, $name: String!, $sha: String!) {
+  repository(owner: $owner, name: $name) {
+    object(expression: $sha) {
+      ... on Commit {
+        associatedPullRequests(first: 100) {
+          nodes { ...PullRequestFields }
+        }
+      }
+    }
+  }
+}
+` + graphQLPullRequestFields
+	variables := c.repositoryVariables()
+	variables["sha"] = sha
+
+	var response struct {
+		Repository struct {
+			Object *struct {
+				AssociatedPullRequests struct {
+					Nodes []graphQLPullRequest `json:"nodes"`
+				} `json:"associatedPullRequests"`
+			} `json:"object"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &response, true); err != nil {
+		return nil, err
+	}
+	if response.Repository.Object == nil {
+		return nil, nil
+	}
+
+	nodes := response.Repository.Object.AssociatedPullRequests.Nodes
+	pullRequests := make([]PullRequest, 0, len(nodes))
+	for _, node := range nodes {
+		pullRequests = append(pullRequests, node.toDomain())
+	}
+	return pullRequests, nil
+}
+
+func (c *GraphQLGitHubClient) lookupRepositoryID(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	repositoryID := c.repositoryID
+	c.mu.Unlock()
+	if repositoryID != "" {
+		return repositoryID, nil
+	}
+
+	query := `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) { id }
+}`
+	var response struct {
+		Repository *graphQLNode `json:"repository"`
+	}
+	if err := c.query(ctx, query, c.repositoryVariables(), &response, false); err != nil {
+		return "", err
+	}
+	if response.Repository == nil || response.Repository.ID == "" {
+		return "", fmt.Errorf("repository %s not found", c.repository.FullName())
+	}
+
+	c.mu.Lock()
+	c.repositoryID = response.Repository.ID
+	c.mu.Unlock()
+	return response.Repository.ID, nil
+}
+
+func (c *GraphQLGitHubClient) lookupPullRequestID(ctx context.Context, number int) (string, error) {
+	query := `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) { id }
+  }
+}`
+	variables := c.repositoryVariables()
+	variables["number"] = number
+
+	var response struct {
+		Repository struct {
+			PullRequest *graphQLNode `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &response, false); err != nil {
+		return "", err
+	}
+	if response.Repository.PullRequest == nil || response.Repository.PullRequest.ID == "" {
+		return "", fmt.Errorf("pull request #%d not found in %s", number, c.repository.FullName())
+	}
+	return response.Repository.PullRequest.ID, nil
+}
+
+func (c *GraphQLGitHubClient) lookupUserIDs(ctx context.Context, logins []string) ([]string, error) {
+	declarations := make([]string, 0, len(logins))
+	fields := make([]string, 0, len(logins))
+	variables := make(map[string]any, len(logins))
+	for idx, login := range logins {
+		declarations = append(declarations, fmt.Sprintf("$u%d: String!", idx))
+		fields = append(fields, fmt.Sprintf("u%d: user(login: $u%d) { id }", idx, idx))
+		variables[fmt.Sprintf("u%d", idx)] = login
+	}
+	query := fmt.Sprintf("query(%s) {\n  %s\n}", strings.Join(declarations, ", "), strings.Join(fields, "\n  "))
+
+	var response map[string]*graphQLNode
+	if err := c.query(ctx, query, variables, &response, false); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(logins))
+	for idx, login := range logins {
+		node := response[fmt.Sprintf("u%d", idx)]
+		if node == nil || node.ID == "" {
+			return nil, fmt.Errorf("user %q not found", login)
+		}
+		userIDs = append(userIDs, node.ID)
+	}
+	return userIDs, nil
+}
+
+func (c *GraphQLGitHubClient) repositoryVariables() map[string]any {
+	return map[string]any{
+		"owner": c.repository.Owner,
+		"name":  c.repository.Name,
+	}
+}
+
+func (c *GraphQLGitHubClient) query(
+	ctx context.Context,
+	query string,
+	variables map[string]any,
+	responseData any,
+	allowNotFound bool,
+) error {
+	endpoint, err := url.Parse(c.endpoint)
+	if err != nil {
+		return fmt.Errorf("parse github graphql url: %w", err)
+	}
+
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphQLError  `json:"errors"`
+	}
+	if !strings.HasPrefix(strings.TrimSpace(query), "mutation") {
+		ctx = withIdempotentRequest(ctx)
+	}
+	if err := c.rest.do(ctx, http.MethodPost, endpoint, map[string]any{
+		"query":     query,
+		"variables": variables,
+	}, &response); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, graphQLErr := range response.Errors {
+		if allowNotFound && graphQLErr.Type == "NOT_FOUND" {
+			continue
+		}
+		errs = append(errs, graphQLErr)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("POST %s: %w", endpoint.Path, errors.Join(errs...))
+	}
+
+	if responseData == nil || len(response.Data) == 0 || string(response.Data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(response.Data, responseData); err != nil {
+		return fmt.Errorf("decode github graphql response: %w", err)
+	}
+	return nil
+}
+
+type graphQLError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+func (e graphQLError) Error() string {
+	if e.Type == "" {
+		return e.Message
+	}
+	return e.Type + ": " + e.Message
+}
+
+type graphQLNode struct {
+	ID string `json:"id"`
+}
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphQLUser struct {
+	Login     string `json:"login"`
+	URL       string `json:"url"`
+	AvatarURL string `json:"avatarUrl"`
+}
+
+func (u graphQLUser) toDomain() User {
+	return User{
+		LoginName: u.Login,
+		URL:       u.URL,
+		Avatar:    u.AvatarURL,
+	}
+}
+
+type graphQLPullRequest struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Body        string     `json:"body"`
+	URL         string     `json:"url"`
+	State       string     `json:"state"`
+	Merged      bool       `json:"merged"`
+	MergedAt    *time.Time `json:"mergedAt"`
+	HeadRefName string     `json:"headRefName"`
+	BaseRefName string     `json:"baseRefName"`
+	MergeCommit *struct {
+		OID string `json:"oid"`
+	} `json:"mergeCommit"`
+	Author    *graphQLUser `json:"author"`
+	Assignees struct {
+		Nodes []graphQLUser `json:"nodes"`
+	} `json:"assignees"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Milestone *struct {
+		Title string `json:"title"`
+	} `json:"milestone"`
+}
+
+func (pr graphQLPullRequest) toDomain() PullRequest {
+	domain := PullRequest{
+		Number:    pr.Number,
+		Title:     pr.Title,
+		Body:      pr.Body,
+		URL:       pr.URL,
+		State:     graphQLPullRequestState(pr.State),
+		Merged:    pr.Merged,
+		HeadRef:   pr.HeadRefName,
+		BaseRef:   pr.BaseRefName,
+		Assignees: make([]User, 0, len(pr.Assignees.Nodes)),
+	}
+	if pr.MergedAt != nil {
+		domain.MergedAt = *pr.MergedAt
+	}
+	if pr.MergeCommit != nil {
+		domain.MergeCommitSHA = pr.MergeCommit.OID
+	}
+	if pr.Author != nil {
+		domain.User = pr.Author.toDomain()
+	}
+	for _, assignee := range pr.Assignees.Nodes {
+		domain.Assignees = append(domain.Assignees, assignee.toDomain())
+	}
+	if len(domain.Assignees) > 0 {
+		assignee := domain.Assignees[0]
+		domain.Assignee = &assignee
+	}
+	for _, label := range pr.Labels.Nodes {
+		domain.Labels = append(domain.Labels, label.Name)
+	}
+	if pr.Milestone != nil {
+		domain.Milestone = pr.Milestone.Title
+	}
+	return domain
+}
+
+func graphQLPullRequestState(state string) string {
+	switch state {
+	case "OPEN":
+		return "open"
+	case "CLOSED", "MERGED":
+		return "closed"
+	default:
+		return strings.ToLower(state)
+	}
+}
+
+func graphQLChangeTypeStatus(changeType string) string {
+	switch changeType {
+	case "DELETED":
+		return "removed"
+	default:
+		return strings.ToLower(changeType)
+	}
+}
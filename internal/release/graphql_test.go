@@ -0,0 +1,427 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var graphQLAliasPattern = regexp.MustCompile(`pr(\d+): pullRequest\(number: \$n\d+\)`)
+
+func TestGraphQLGitHubClientGetPullRequestsBatchesBeyondOneHundredPullRequests(t *testing.T) {
+	t.Parallel()
+
+	client, requests := newGraphQLPullRequestClient(t, 101)
+	numbers := sequence(1, 101)
+
+	pullRequests, err := client.GetPullRequests(context.Background(), numbers)
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+
+	if got := pullRequestNumbers(pullRequests); !reflect.DeepEqual(got, numbers) {
+		t.Fatalf("got %v, want %v", got, numbers)
+	}
+	if *requests != 3 {
+		t.Fatalf("got %d requests, want 3", *requests)
+	}
+	if got := pullRequests[0]; got.User.LoginName != "author1" || !got.Merged || got.MergeCommitSHA != "sha1" {
+		t.Fatalf("unexpected pull request: %+v", got)
+	}
+	if got := pullRequests[0].TargetUserLoginNames(""); !reflect.DeepEqual(got, []string{"assignee1"}) {
+		t.Fatalf("unexpected assignees: %v", got)
+	}
+}
+
+func TestGraphQLGitHubClientGetPullRequestsAvoidsOneRequestPerPullRequestBeyondOneThousand(t *testing.T) {
+	t.Parallel()
+
+	client, requests := newGraphQLPullRequestClient(t, 1001)
+	numbers := sequence(1, 1001)
+
+	pullRequests, err := client.GetPullRequests(context.Background(), numbers)
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+
+	if got := len(pullRequests); got != 1001 {
+		t.Fatalf("got %d pull requests, want 1001", got)
+	}
+	if *requests != 21 {
+		t.Fatalf("got %d requests, want 21", *requests)
+	}
+}
+
+func TestGraphQLGitHubClientGetPullRequestsSkipsMissingPullRequests(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newGraphQLPullRequestClient(t, 2)
+
+	pullRequests, err := client.GetPullRequests(context.Background(), []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGraphQLGitHubClientSearchPullRequestNumbersPaginatesBeyondOneHundredResults(t *testing.T) {
+	t.Parallel()
+
+	searchRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+
+		searchRequests++
+		request := decodeGraphQLRequest(t, r)
+		if got := request.Variables["q"]; got != "repo:octo/example is:pr is:closed abcdef0" {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+
+		var nodes []map[string]int
+		pageInfo := map[string]any{"hasNextPage": false, "endCursor": ""}
+		switch request.Variables["after"] {
+		case nil:
+			nodes = makeGraphQLSearchNodes(1, 100)
+			pageInfo = map[string]any{"hasNextPage": true, "endCursor": "cursor1"}
+		case "cursor1":
+			nodes = makeGraphQLSearchNodes(101, 125)
+		}
+		writeGraphQLData(w, map[string]any{"search": map[string]any{"nodes": nodes, "pageInfo": pageInfo}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	numbers, err := client.SearchPullRequestNumbers(context.Background(), "repo:octo/example is:pr is:closed abcdef0")
+	if err != nil {
+		t.Fatalf("search pull request numbers: %v", err)
+	}
+
+	if got, want := numbers, sequence(1, 125); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got[:5], want[:5])
+	}
+	if searchRequests != 2 {
+		t.Fatalf("got %d requests, want 2", searchRequests)
+	}
+}
+
+func TestGraphQLGitHubClientSearchPullRequestNumbersRetriesOnRateLimit(t *testing.T) {
+	t.Parallel()
+
+	searchRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+
+		searchRequests++
+		if searchRequests == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "secondary rate limit", http.StatusForbidden)
+			return
+		}
+
+		writeGraphQLData(w, map[string]any{"search": map[string]any{
+			"nodes":    makeGraphQLSearchNodes(1, 2),
+			"pageInfo": map[string]any{"hasNextPage": false},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	numbers, err := client.SearchPullRequestNumbers(context.Background(), "repo:octo/example is:pr is:closed abcdef0")
+	if err != nil {
+		t.Fatalf("search pull request numbers: %v", err)
+	}
+
+	if got, want := numbers, []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if searchRequests != 2 {
+		t.Fatalf("got %d requests, want 2", searchRequests)
+	}
+}
+
+func TestGraphQLGitHubClientAddLabelsResolvesLabelIDs(t *testing.T) {
+	t.Parallel()
+
+	var mutationInput map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := decodeGraphQLRequest(t, r)
+		switch {
+		case strings.Contains(request.Query, "pullRequest(number: $number) { id }"):
+			writeGraphQLData(w, map[string]any{"repository": map[string]any{"pullRequest": map[string]string{"id": "PR_99"}}})
+		case strings.Contains(request.Query, "label(name:"):
+			writeGraphQLData(w, map[string]any{"repository": map[string]any{
+				"l0": map[string]string{"id": "LA_release"},
+				"l1": map[string]string{"id": "LA_qa"},
+			}})
+		case strings.Contains(request.Query, "addLabelsToLabelable"):
+			mutationInput, _ = request.Variables["input"].(map[string]any)
+			writeGraphQLData(w, map[string]any{"addLabelsToLabelable": map[string]any{}})
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	if err := client.AddLabels(context.Background(), 99, []string{"release", "qa"}); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+
+	if got := mutationInput["labelableId"]; got != "PR_99" {
+		t.Fatalf("unexpected labelable id: %v", got)
+	}
+	if got := mutationInput["labelIds"]; !reflect.DeepEqual(got, []any{"LA_release", "LA_qa"}) {
+		t.Fatalf("unexpected label ids: %v", got)
+	}
+}
+
+func TestGraphQLGitHubClientAddLabelsCreatesMissingLabels(t *testing.T) {
+	t.Parallel()
+
+	var mutationInput map[string]any
+	var restLabels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/repos/octo/example/issues/99/labels" {
+			var request struct {
+				Labels []string `json:"labels"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Errorf("decode labels request: %v", err)
+			}
+			restLabels = request.Labels
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+
+		request := decodeGraphQLRequest(t, r)
+		switch {
+		case strings.Contains(request.Query, "pullRequest(number: $number) { id }"):
+			writeGraphQLData(w, map[string]any{"repository": map[string]any{"pullRequest": map[string]string{"id": "PR_99"}}})
+		case strings.Contains(request.Query, "label(name:"):
+			writeGraphQLData(w, map[string]any{"repository": map[string]any{
+				"l0": map[string]string{"id": "LA_release"},
+				"l1": nil,
+			}})
+		case strings.Contains(request.Query, "addLabelsToLabelable"):
+			mutationInput, _ = request.Variables["input"].(map[string]any)
+			writeGraphQLData(w, map[string]any{"addLabelsToLabelable": map[string]any{}})
+		default:
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	if err := client.AddLabels(context.Background(), 99, []string{"release", "qa"}); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+
+	if got := mutationInput["labelIds"]; !reflect.DeepEqual(got, []any{"LA_release"}) {
+		t.Fatalf("unexpected label ids: %v", got)
+	}
+	if !reflect.DeepEqual(restLabels, []string{"qa"}) {
+		t.Fatalf("expected the missing label to be created through REST, got %v", restLabels)
+	}
+}
+
+func TestGraphQLGitHubClientUpdatePullRequestReportsMissingPullRequest(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data":   map[string]any{"repository": map[string]any{"pullRequest": nil}},
+			"errors": []map[string]any{{"type": "NOT_FOUND", "path": []string{"repository", "pullRequest"}, "message": "Could not resolve to a PullRequest with the number of 42."}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	_, err := client.UpdatePullRequest(context.Background(), 42, "title", "body")
+	if err == nil || !strings.Contains(err.Error(), "NOT_FOUND") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGraphQLGitHubClientListPullRequestsForCommit(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := decodeGraphQLRequest(t, r)
+		if request.Variables["sha"] != "abcdef0123" {
+			writeGraphQLData(w, map[string]any{"repository": map[string]any{"object": nil}})
+			return
+		}
+		writeGraphQLData(w, map[string]any{"repository": map[string]any{"object": map[string]any{
+			"associatedPullRequests": map[string]any{"nodes": []map[string]any{
+				{"number": 7, "title": "feature", "merged": true, "mergeCommit": map[string]string{"oid": "abcdef0123"}},
+			}},
+		}}})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	pullRequests, err := client.ListPullRequestsForCommit(context.Background(), "abcdef0123")
+	if err != nil {
+		t.Fatalf("list pull requests for commit: %v", err)
+	}
+	if got, want := pullRequestNumbers(pullRequests), []int{7}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if pullRequests[0].MergeCommitSHA != "abcdef0123" {
+		t.Fatalf("unexpected merge commit sha: %q", pullRequests[0].MergeCommitSHA)
+	}
+
+	pullRequests, err = client.ListPullRequestsForCommit(context.Background(), "0000000")
+	if err != nil || len(pullRequests) != 0 {
+		t.Fatalf("expected no pull requests for an unknown commit, got %v, %v", pullRequests, err)
+	}
+}
+
+func TestGraphQLGitHubClientDecodesLabelsAndMilestone(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data": {"repository": {"pr7": {
+			"number": 7,
+			"merged": true,
+			"labels": {"nodes": [{"name": "feature"}, {"name": "skip-release-notes"}]},
+			"milestone": {"title": "v1.2.0"}
+		}}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	pullRequests, err := client.GetPullRequests(context.Background(), []int{7})
+	if err != nil {
+		t.Fatalf("get pull requests: %v", err)
+	}
+	if len(pullRequests) != 1 {
+		t.Fatalf("got %d pull requests, want 1", len(pullRequests))
+	}
+	if got, want := pullRequests[0].Labels, []string{"feature", "skip-release-notes"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got labels %v, want %v", got, want)
+	}
+	if pullRequests[0].Milestone != "v1.2.0" {
+		t.Fatalf("unexpected milestone: %q", pullRequests[0].Milestone)
+	}
+}
+
+func TestGraphQLGitHubClientReturnsGraphQLErrors(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data":   nil,
+			"errors": []map[string]string{{"type": "FORBIDDEN", "message": "Resource not accessible by integration"}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGraphQLClient(server)
+
+	_, err := client.ListOpenReleasePullRequests(context.Background(), "octo:staging", "master")
+	if err == nil || !strings.Contains(err.Error(), "Resource not accessible by integration") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type graphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func decodeGraphQLRequest(t *testing.T, r *http.Request) graphQLRequest {
+	t.Helper()
+
+	var request graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		t.Errorf("decode graphql request: %v", err)
+	}
+	return request
+}
+
+func writeGraphQLData(w http.ResponseWriter, data any) {
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func newGraphQLPullRequestClient(t *testing.T, total int) (*GraphQLGitHubClient, *int) {
+	t.Helper()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			http.NotFound(w, r)
+			return
+		}
+
+		requests++
+		request := decodeGraphQLRequest(t, r)
+		repository := map[string]any{}
+		var errs []map[string]any
+		for _, matches := range graphQLAliasPattern.FindAllStringSubmatch(request.Query, -1) {
+			var number int
+			_, _ = fmt.Sscanf(matches[1], "%d", &number)
+			alias := "pr" + matches[1]
+			if number > total {
+				repository[alias] = nil
+				errs = append(errs, map[string]any{"type": "NOT_FOUND", "path": []string{"repository", alias}, "message": "not found"})
+				continue
+			}
+			repository[alias] = map[string]any{
+				"number":      number,
+				"title":       fmt.Sprintf("PR %d", number),
+				"url":         fmt.Sprintf("https://example.com/pulls/%d", number),
+				"state":       "MERGED",
+				"merged":      true,
+				"mergeCommit": map[string]string{"oid": fmt.Sprintf("sha%d", number)},
+				"author":      map[string]string{"login": fmt.Sprintf("author%d", number)},
+				"assignees":   map[string]any{"nodes": []map[string]string{{"login": fmt.Sprintf("assignee%d", number)}}},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"repository": repository}, "errors": errs})
+	}))
+	t.Cleanup(server.Close)
+
+	return newTestGraphQLClient(server), &requests
+}
+
+func newTestGraphQLClient(server *httptest.Server) *GraphQLGitHubClient {
+	rest := newTestGitHubClient(server)
+	return &GraphQLGitHubClient{
+		rest:       rest,
+		endpoint:   server.URL + "/api/graphql",
+		repository: rest.repository,
+	}
+}
+
+func makeGraphQLSearchNodes(start, end int) []map[string]int {
+	nodes := make([]map[string]int, 0, end-start+1)
+	for number := start; number <= end; number++ {
+		nodes = append(nodes, map[string]int{"number": number})
+	}
+	return nodes
+}
@@ -0,0 +1,94 @@
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	manualStartPattern = regexp.MustCompile(`^\s*<!--\s*go-pr-release:manual:start(?:\s+([\w.-]+))?\s*-->\s*$`)
+	manualEndPattern   = regexp.MustCompile(`^\s*<!--\s*go-pr-release:manual:end(?:\s+[\w.-]+)?\s*-->\s*$`)
+)
+
+// manualPlaceholder stands in for a whole manual region while the rest of the
+// body is diffed. The NUL byte keeps it from colliding with real lines.
+const manualPlaceholder = "\x00go-pr-release:manual:"
+
+// extractManualRegions replaces every "<!-- go-pr-release:manual:start name -->"
+// ... "<!-- go-pr-release:manual:end -->" block with a placeholder line and
+// returns the blocks, markers included, keyed by name. Repeated names are
+// numbered in order of appearance. A start marker without an end is left as
+// ordinary text.
+func extractManualRegions(body string) (string, map[string][]string) {
+	lines := splitLines(body)
+	regions := map[string][]string{}
+	seen := map[string]int{}
+	rest := make([]string, 0, len(lines))
+	for idx := 0; idx < len(lines); idx++ {
+		match := manualStartPattern.FindStringSubmatch(lines[idx])
+		if match == nil {
+			rest = append(rest, lines[idx])
+			continue
+		}
+		end := -1
+		for next := idx + 1; next < len(lines); next++ {
+			if manualEndPattern.MatchString(lines[next]) {
+				end = next
+				break
+			}
+		}
+		if end < 0 {
+			rest = append(rest, lines[idx])
+			continue
+		}
+
+		key := match[1]
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, seen[key])
+		}
+		regions[key] = lines[idx : end+1]
+		rest = append(rest, manualPlaceholder+key)
+		idx = end
+	}
+	return strings.Join(rest, "\n"), regions
+}
+
+// dropManualPlaceholders removes placeholders for regions that the new body
+// also has, so that those regions follow the template's anchor instead of
+// their old position.
+func dropManualPlaceholders(body string, keep func(key string) bool) string {
+	lines := splitLines(body)
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if key, ok := strings.CutPrefix(line, manualPlaceholder); ok && !keep(key) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// restoreManualRegions expands placeholders, preferring the old region's text
+// over the template's default.
+func restoreManualRegions(body string, oldRegions, newRegions map[string][]string) string {
+	if len(oldRegions) == 0 && len(newRegions) == 0 {
+		return body
+	}
+	lines := splitLines(body)
+	restored := make([]string, 0, len(lines))
+	for _, line := range lines {
+		key, ok := strings.CutPrefix(line, manualPlaceholder)
+		if !ok {
+			restored = append(restored, line)
+			continue
+		}
+		if region, ok := oldRegions[key]; ok {
+			restored = append(restored, region...)
+			continue
+		}
+		restored = append(restored, newRegions[key]...)
+	}
+	return strings.Join(restored, "\n")
+}
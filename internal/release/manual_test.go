@@ -0,0 +1,99 @@
+package release
+
+import "testing"
+
+func TestMergeBodiesPreservesManualRegions(t *testing.T) {
+	t.Parallel()
+
+	const qaNotes = `<!-- go-pr-release:manual:start qa -->
+QA: checked on staging by @alice
+- [ ] #99 is not a release entry
+<!-- go-pr-release:manual:end -->`
+	const qaDefault = `<!-- go-pr-release:manual:start qa -->
+_Add QA notes here_
+<!-- go-pr-release:manual:end -->`
+
+	tests := []struct {
+		name    string
+		oldBody string
+		newBody string
+		want    string
+	}{
+		{
+			name:    "template default on first render",
+			newBody: "# Release\n- [ ] #1 One\n" + qaDefault,
+			want:    "# Release\n- [ ] #1 One\n" + qaDefault,
+		},
+		{
+			name:    "pull request added",
+			oldBody: "# Release\n- [x] #1 One\n" + qaNotes,
+			newBody: "# Release\n- [ ] #1 One\n- [ ] #2 Two\n" + qaDefault,
+			want:    "# Release\n- [x] #1 One\n- [ ] #2 Two\n" + qaNotes,
+		},
+		{
+			name:    "pull request removed",
+			oldBody: "# Release\n- [x] #1 One\n- [ ] #2 Two\n" + qaNotes,
+			newBody: "# Release\n- [ ] #2 Two\n" + qaDefault,
+			want:    "# Release\n- [x] #1 One\n- [ ] #2 Two\n" + qaNotes,
+		},
+		{
+			name:    "pull requests reordered",
+			oldBody: "# Release\n- [x] #1 One\n- [ ] #2 Two\n" + qaNotes,
+			newBody: "# Release\n- [ ] #2 Two\n- [ ] #1 One\n" + qaDefault,
+			want:    "# Release\n- [ ] #2 Two\n- [x] #1 One\n" + qaNotes,
+		},
+		{
+			name:    "pull request replaced",
+			oldBody: "# Release\n- [x] #1 One\n" + qaNotes,
+			newBody: "# Release\n- [ ] #2 Two\n" + qaDefault,
+			want:    "# Release\n- [x] #1 One\n- [ ] #2 Two\n" + qaNotes,
+		},
+		{
+			name:    "pull request title changed",
+			oldBody: "# Release\n- [x] #1 One\n" + qaNotes,
+			newBody: "# Release\n- [ ] #1 One (renamed)\n" + qaDefault,
+			want:    "# Release\n- [x] #1 One (renamed)\n" + qaNotes,
+		},
+		{
+			name:    "region moved in the template",
+			oldBody: qaNotes + "\n# Release\n- [x] #1 One",
+			newBody: "# Release\n- [ ] #1 One\n" + qaDefault,
+			want:    "# Release\n- [x] #1 One\n" + qaNotes,
+		},
+		{
+			name:    "region dropped from the template stays in place",
+			oldBody: "# Release\n" + qaNotes + "\n- [x] #1 One",
+			newBody: "# Release\n- [ ] #1 One",
+			want:    "# Release\n" + qaNotes + "\n- [x] #1 One",
+		},
+		{
+			name: "several regions",
+			oldBody: "<!-- go-pr-release:manual:start deploy -->\n1. run migrations\n<!-- go-pr-release:manual:end -->\n" +
+				"- [x] #1 One\n" + qaNotes,
+			newBody: "- [ ] #1 One\n- [ ] #3 Three\n" + qaDefault +
+				"\n<!-- go-pr-release:manual:start deploy -->\n<!-- go-pr-release:manual:end -->",
+			want: "- [x] #1 One\n- [ ] #3 Three\n" + qaNotes +
+				"\n<!-- go-pr-release:manual:start deploy -->\n1. run migrations\n<!-- go-pr-release:manual:end -->",
+		},
+		{
+			name:    "unterminated marker is ordinary text",
+			oldBody: "<!-- go-pr-release:manual:start -->\nnote\n- [x] #1 One",
+			newBody: "<!-- go-pr-release:manual:start -->\n- [ ] #1 One",
+			want:    "<!-- go-pr-release:manual:start -->\nnote\n- [x] #1 One",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := MergeBodies(tt.oldBody, tt.newBody, Repository{Owner: "octo", Name: "example"})
+			if got != tt.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if again := MergeBodies(got, tt.newBody, Repository{Owner: "octo", Name: "example"}); again != got {
+				t.Fatalf("merging again changed the body:\n%s", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MergeClient reads the mergeability of a pull request and merges it. Only
+// the GitHub clients implement it.
+type MergeClient interface {
+	GetMergeStatus(ctx context.Context, number int) (MergeStatus, error)
+	MergePullRequest(ctx context.Context, number int, method, headSHA string) error
+	EnableAutoMerge(ctx context.Context, number int, method, headSHA string) error
+}
+
+// MergeStatus carries the GraphQL enums as is. Only required status checks
+// are listed.
+type MergeStatus struct {
+	HeadSHA        string   `json:"head_sha"`
+	Mergeable      string   `json:"mergeable"`
+	MergeState     string   `json:"merge_state"`
+	ReviewDecision string   `json:"review_decision,omitempty"`
+	AutoMerge      bool     `json:"auto_merge"`
+	FailingChecks  []string `json:"failing_checks,omitempty"`
+	PendingChecks  []string `json:"pending_checks,omitempty"`
+}
+
+func (c *GraphQLGitHubClient) GetMergeStatus(ctx context.Context, number int) (MergeStatus, error) {
+	query := `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      mergeable
+      mergeStateStatus
+      reviewDecision
+      autoMergeRequest { enabledAt }
+      commits(last: 1) {
+        nodes {
+          commit {
+            oid
+            statusCheckRollup {
+              contexts(first: 100) {
+                nodes {
+                  __typename
+                  ... on CheckRun { name status conclusion isRequired(pullRequestNumber: $number) }
+                  ... on StatusContext { context state isRequired(pullRequestNumber: $number) }
+                }
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+	variables := c.repositoryVariables()
+	variables["number"] = number
+
+	var response struct {
+		Repository struct {
+			PullRequest *struct {
+				Mergeable        string `json:"mergeable"`
+				MergeStateStatus string `json:"mergeStateStatus"`
+				ReviewDecision   string `json:"reviewDecision"`
+				AutoMergeRequest *struct {
+					EnabledAt string `json:"enabledAt"`
+				} `json:"autoMergeRequest"`
+				Commits struct {
+					Nodes []struct {
+						Commit struct {
+							OID               string `json:"oid"`
+							StatusCheckRollup *struct {
+								Contexts struct {
+									Nodes []graphQLCheckContext `json:"nodes"`
+								} `json:"contexts"`
+							} `json:"statusCheckRollup"`
+						} `json:"commit"`
+					} `json:"nodes"`
+				} `json:"commits"`
+			} `json:"pullRequest"`
+		} `json:"repository"`
+	}
+	if err := c.query(ctx, query, variables, &response, false); err != nil {
+		return MergeStatus{}, err
+	}
+	pr := response.Repository.PullRequest
+	if pr == nil {
+		return MergeStatus{}, fmt.Errorf("pull request #%d not found in %s", number, c.repository.FullName())
+	}
+
+	status := MergeStatus{
+		Mergeable:      pr.Mergeable,
+		MergeState:     pr.MergeStateStatus,
+		ReviewDecision: pr.ReviewDecision,
+		AutoMerge:      pr.AutoMergeRequest != nil,
+	}
+	if len(pr.Commits.Nodes) == 0 {
+		return status, nil
+	}
+	commit := pr.Commits.Nodes[0].Commit
+	status.HeadSHA = commit.OID
+	if commit.StatusCheckRollup == nil {
+		return status, nil
+	}
+	for _, check := range commit.StatusCheckRollup.Contexts.Nodes {
+		if !check.IsRequired {
+			continue
+		}
+		switch check.result() {
+		case checkResultFailing:
+			status.FailingChecks = append(status.FailingChecks, check.name())
+		case checkResultPending:
+			status.PendingChecks = append(status.PendingChecks, check.name())
+		}
+	}
+	return status, nil
+}
+
+func (c *GraphQLGitHubClient) MergePullRequest(ctx context.Context, number int, method, headSHA string) error {
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return err
+	}
+	mutation := `mutation($input: MergePullRequestInput!) {
+  mergePullRequest(input: $input) { clientMutationId }
+}`
+	return c.query(ctx, mutation, map[string]any{
+		"input": map[string]string{
+			"pullRequestId":   pullRequestID,
+			"mergeMethod":     strings.ToUpper(method),
+			"expectedHeadOid": headSHA,
+		},
+	}, nil, false)
+}
+
+func (c *GraphQLGitHubClient) EnableAutoMerge(ctx context.Context, number int, method, headSHA string) error {
+	pullRequestID, err := c.lookupPullRequestID(ctx, number)
+	if err != nil {
+		return err
+	}
+	mutation := `mutation($input: EnablePullRequestAutoMergeInput!) {
+  enablePullRequestAutoMerge(input: $input) { clientMutationId }
+}`
+	return c.query(ctx, mutation, map[string]any{
+		"input": map[string]string{
+			"pullRequestId":   pullRequestID,
+			"mergeMethod":     strings.ToUpper(method),
+			"expectedHeadOid": headSHA,
+		},
+	}, nil, false)
+}
+
+// Required checks and auto-merge are only exposed by the GraphQL API, so the
+// REST client delegates to a GraphQL client sharing its transport.
+func (c *RESTGitHubClient) GetMergeStatus(ctx context.Context, number int) (MergeStatus, error) {
+	return c.graphQL().GetMergeStatus(ctx, number)
+}
+
+func (c *RESTGitHubClient) MergePullRequest(ctx context.Context, number int, method, headSHA string) error {
+	return c.graphQL().MergePullRequest(ctx, number, method, headSHA)
+}
+
+func (c *RESTGitHubClient) EnableAutoMerge(ctx context.Context, number int, method, headSHA string) error {
+	return c.graphQL().EnableAutoMerge(ctx, number, method, headSHA)
+}
+
+func (c *RESTGitHubClient) graphQL() *GraphQLGitHubClient {
+	return &GraphQLGitHubClient{
+		rest:       c,
+		endpoint:   c.repository.GraphQLURL(),
+		repository: c.repository,
+	}
+}
+
+const (
+	checkResultPassing = "passing"
+	checkResultPending = "pending"
+	checkResultFailing = "failing"
+)
+
+type graphQLCheckContext struct {
+	TypeName   string `json:"__typename"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	Context    string `json:"context"`
+	State      string `json:"state"`
+	IsRequired bool   `json:"isRequired"`
+}
+
+func (c graphQLCheckContext) name() string {
+	if c.TypeName == "StatusContext" {
+		return c.Context
+	}
+	return c.Name
+}
+
+func (c graphQLCheckContext) result() string {
+	if c.TypeName == "StatusContext" {
+		switch c.State {
+		case "SUCCESS":
+			return checkResultPassing
+		case "FAILURE", "ERROR":
+			return checkResultFailing
+		default:
+			return checkResultPending
+		}
+	}
+	if c.Status != "COMPLETED" {
+		return checkResultPending
+	}
+	switch c.Conclusion {
+	case "FAILURE", "TIMED_OUT", "CANCELLED", "ACTION_REQUIRED", "STARTUP_FAILURE":
+		return checkResultFailing
+	default:
+		return checkResultPassing
+	}
+}
@@ -0,0 +1,112 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// pathFilter limits a release to the files under IncludePaths, minus the
+// files under ExcludePaths. Patterns use path.Match syntax per segment, "**"
+// matches any number of segments, and a pattern matching a directory matches
+// everything below it.
+type pathFilter struct {
+	include []string
+	exclude []string
+}
+
+func (c Config) pathFilter() (pathFilter, error) {
+	for _, pattern := range append(append([]string(nil), c.IncludePaths...), c.ExcludePaths...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return pathFilter{}, fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+	return pathFilter{include: c.IncludePaths, exclude: c.ExcludePaths}, nil
+}
+
+func (f pathFilter) active() bool {
+	return len(f.include) > 0 || len(f.exclude) > 0
+}
+
+func (f pathFilter) match(name string) bool {
+	if len(f.include) > 0 && !matchAnyPathPattern(f.include, name) {
+		return false
+	}
+	return !matchAnyPathPattern(f.exclude, name)
+}
+
+func (f pathFilter) files(files []ChangedFile) []ChangedFile {
+	if !f.active() || files == nil {
+		return files
+	}
+	filtered := make([]ChangedFile, 0, len(files))
+	for _, file := range files {
+		if f.match(file.Filename) {
+			filtered = append(filtered, file)
+		}
+	}
+	return filtered
+}
+
+func (s *Service) filterPullRequestsByPaths(ctx context.Context, filter pathFilter, pullRequests []PullRequest) ([]PullRequest, error) {
+	touched := make([]bool, len(pullRequests))
+	err := forEachConcurrently(ctx, len(pullRequests), commitLookupConcurrency, func(ctx context.Context, idx int) error {
+		files, err := s.github.ListPullRequestFiles(ctx, pullRequests[idx].Number)
+		if err != nil {
+			return err
+		}
+		touched[idx] = len(filter.files(files)) > 0
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]PullRequest, 0, len(pullRequests))
+	for idx, pr := range pullRequests {
+		if touched[idx] {
+			filtered = append(filtered, pr)
+		}
+	}
+	return filtered, nil
+}
+
+func matchAnyPathPattern(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPathPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPathPattern(pattern, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	return matchPathSegments(strings.Split(pattern, "/"), strings.Split(strings.Trim(name, "/"), "/"))
+}
+
+func matchPathSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for idx := 0; idx <= len(name); idx++ {
+				if matchPathSegments(pattern[1:], name[idx:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	// Leftover name segments mean the pattern matched a parent directory.
+	return true
+}
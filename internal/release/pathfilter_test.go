@@ -0,0 +1,105 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPathFilterMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{name: "no patterns", path: "README.md", want: true},
+		{name: "directory prefix", include: []string{"services/api"}, path: "services/api/main.go", want: true},
+		{name: "trailing slash", include: []string{"services/api/"}, path: "services/api/handler/user.go", want: true},
+		{name: "sibling directory", include: []string{"services/api"}, path: "services/apiserver/main.go", want: false},
+		{name: "single segment glob", include: []string{"services/*/go.mod"}, path: "services/web/go.mod", want: true},
+		{name: "double star", include: []string{"**/*.proto"}, path: "proto/user/v1/user.proto", want: true},
+		{name: "double star matches zero segments", include: []string{"services/**/main.go"}, path: "services/main.go", want: true},
+		{name: "double star miss", include: []string{"**/*.proto"}, path: "proto/user/v1/user.go", want: false},
+		{name: "exclude wins", include: []string{"services/api"}, exclude: []string{"**/*_test.go"}, path: "services/api/main_test.go", want: false},
+		{name: "exclude only", exclude: []string{"docs"}, path: "docs/index.md", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			filter, err := Config{IncludePaths: tt.include, ExcludePaths: tt.exclude}.pathFilter()
+			if err != nil {
+				t.Fatalf("path filter: %v", err)
+			}
+			if got := filter.match(tt.path); got != tt.want {
+				t.Fatalf("match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathFilterRejectsInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, err := (Config{IncludePaths: []string{"services/[api"}}).pathFilter(); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+}
+
+func TestServiceFetchMergedPullRequestsFiltersByPaths(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "feature1", Merged: true},
+		},
+		changedFiles: map[int][]ChangedFile{
+			1: {{Filename: "services/api/main.go"}, {Filename: "services/web/main_test.go"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []int
+	}{
+		{name: "touches included path", include: []string{"services/api"}, want: []int{1}},
+		{name: "only touches excluded files", include: []string{"services/web"}, exclude: []string{"**/*_test.go"}, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewServiceWithClients(Config{
+				WorkDir:          workDir,
+				RemoteName:       DefaultRemoteName,
+				Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+				Token:            "dummy",
+				ProductionBranch: "master",
+				StagingBranch:    "staging",
+				NoFetch:          true,
+				IncludePaths:     tt.include,
+				ExcludePaths:     tt.exclude,
+			}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
+
+			filter, err := service.config.pathFilter()
+			if err != nil {
+				t.Fatalf("path filter: %v", err)
+			}
+			pullRequests, err := service.fetchMergedPullRequests(context.Background(), filter)
+			if err != nil {
+				t.Fatalf("fetch merged pull requests: %v", err)
+			}
+			if got := pullRequestNumbers(pullRequests); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+const diffContextLines = 3
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// releasePreview is what run would apply to the release pull request.
+type releasePreview struct {
+	Number    int          `json:"number,omitempty"`
+	Create    bool         `json:"create"`
+	Changed   bool         `json:"changed"`
+	Title     titleChange  `json:"title"`
+	Body      string       `json:"body"`
+	BodyHunks []diffHunk   `json:"body_hunks"`
+	Labels    valuesChange `json:"labels"`
+	Assignees valuesChange `json:"assignees"`
+	Reviewers valuesChange `json:"reviewers"`
+}
+
+type titleChange struct {
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Changed bool   `json:"changed"`
+}
+
+// valuesChange lists values run would add. Labels, assignees and reviewers
+// are only ever added, never removed.
+type valuesChange struct {
+	Current []string `json:"current,omitempty"`
+	Added   []string `json:"added"`
+}
+
+type diffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// preview renders the release pull request without creating or updating it
+// and prints how it differs from the open one.
+func (s *Service) preview(ctx context.Context) error {
+	filter, err := s.config.pathFilter()
+	if err != nil {
+		return err
+	}
+	mergedPRs, err := s.fetchMergedPullRequests(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if len(mergedPRs) == 0 {
+		s.say("No pull requests to be released")
+		return ErrNoPullRequestsToRelease
+	}
+	version, err := s.suggestVersion(ctx, s.config.remoteBranch(s.config.ProductionBranch), mergedPRs)
+	if err != nil {
+		return err
+	}
+	root, err := s.git.Root(ctx)
+	if err != nil {
+		return err
+	}
+
+	existingPR, err := s.detectExistingReleasePullRequest(ctx)
+	if err != nil {
+		return err
+	}
+	var changedFiles []ChangedFile
+	if existingPR != nil {
+		changedFiles, err = s.github.ListPullRequestFiles(ctx, existingPR.Number)
+		if err != nil {
+			return err
+		}
+	}
+
+	title, body, err := s.renderReleasePullRequest(root, existingPR, mergedPRs, filter.files(changedFiles), version)
+	if err != nil {
+		return err
+	}
+	preview := s.previewReleasePullRequest(existingPR, mergedPRs, title, body)
+
+	if s.config.JSON {
+		encoder := json.NewEncoder(s.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(preview)
+	}
+
+	fmt.Fprintln(s.stdout, title)
+	fmt.Fprintln(s.stdout)
+	fmt.Fprintln(s.stdout, body)
+	fmt.Fprintln(s.stdout)
+	if existingPR == nil {
+		s.say("No open release pull request; run would create one")
+	}
+	preview.write(s.stdout, s.colorEnabled(s.stdout))
+	return nil
+}
+
+func (s *Service) previewReleasePullRequest(existingPR *PullRequest, mergedPRs []PullRequest, title, body string) releasePreview {
+	preview := releasePreview{Create: existingPR == nil, Body: body}
+	current := PullRequest{}
+	if existingPR != nil {
+		current = *existingPR
+		preview.Number = existingPR.Number
+	}
+
+	preview.Title = titleChange{Old: current.Title, New: title, Changed: current.Title != title}
+	preview.BodyHunks = diffHunks(diffLines(splitLines(current.Body), splitLines(body)), diffContextLines)
+
+	preview.Labels = newValuesChange(current.Labels, s.config.Labels)
+	currentAssignees := make([]string, 0, len(current.Assignees))
+	for _, assignee := range current.Assignees {
+		currentAssignees = append(currentAssignees, assignee.LoginName)
+	}
+	preview.Assignees = newValuesChange(currentAssignees, s.releaseAssignees(mergedPRs))
+	// Requested reviewers are not read back, so every reviewer is listed.
+	preview.Reviewers = newValuesChange(nil, s.releaseReviewers(mergedPRs))
+
+	preview.Changed = preview.Create || preview.Title.Changed || len(preview.BodyHunks) > 0 ||
+		len(preview.Labels.Added) > 0 || len(preview.Assignees.Added) > 0 || len(preview.Reviewers.Added) > 0
+	return preview
+}
+
+func newValuesChange(current, wanted []string) valuesChange {
+	change := valuesChange{Current: current, Added: []string{}}
+	for _, value := range uniqueStrings(wanted) {
+		if !slices.Contains(current, value) {
+			change.Added = append(change.Added, value)
+		}
+	}
+	return change
+}
+
+// write prints the preview as a unified diff. Title, labels, assignees and
+// reviewers get their own named hunks after the body.
+func (p releasePreview) write(w io.Writer, color bool) {
+	oldName := "/dev/null"
+	if !p.Create {
+		oldName = fmt.Sprintf("#%d", p.Number)
+	}
+	if !p.Changed {
+		fmt.Fprintf(w, "No changes to %s\n", oldName)
+		return
+	}
+
+	paint := func(code, line string) {
+		if color {
+			line = code + line + colorReset
+		}
+		fmt.Fprintln(w, line)
+	}
+	writeLine := func(line string) {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			paint(colorRed, line)
+		case strings.HasPrefix(line, "+"):
+			paint(colorGreen, line)
+		default:
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	paint(colorBold, "--- "+oldName)
+	paint(colorBold, "+++ preview")
+	if p.Title.Changed {
+		paint(colorCyan, "@@ title @@")
+		if p.Title.Old != "" {
+			writeLine("-" + p.Title.Old)
+		}
+		writeLine("+" + p.Title.New)
+	}
+	for _, hunk := range p.BodyHunks {
+		paint(colorCyan, hunk.header())
+		for _, line := range hunk.Lines {
+			writeLine(line)
+		}
+	}
+	for _, values := range []struct {
+		name   string
+		change valuesChange
+	}{
+		{name: "labels", change: p.Labels},
+		{name: "assignees", change: p.Assignees},
+		{name: "reviewers", change: p.Reviewers},
+	} {
+		if len(values.change.Added) == 0 {
+			continue
+		}
+		paint(colorCyan, "@@ "+values.name+" @@")
+		for _, value := range values.change.Current {
+			writeLine(" " + value)
+		}
+		for _, value := range values.change.Added {
+			writeLine("+" + value)
+		}
+	}
+}
+
+func (h diffHunk) header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// diffHunks groups ops into unified diff hunks with context unchanged lines
+// around each change. Changes closer than twice the context share a hunk.
+func diffHunks(ops []diffOp, context int) []diffHunk {
+	oldAt := make([]int, len(ops)+1)
+	newAt := make([]int, len(ops)+1)
+	var changes []int
+	for i, op := range ops {
+		oldAt[i+1], newAt[i+1] = oldAt[i], newAt[i]
+		if op.kind != diffInsert {
+			oldAt[i+1]++
+		}
+		if op.kind != diffDelete {
+			newAt[i+1]++
+		}
+		if op.kind != diffEqual {
+			changes = append(changes, i)
+		}
+	}
+
+	hunks := []diffHunk{}
+	for i := 0; i < len(changes); {
+		last := i
+		for last+1 < len(changes) && changes[last+1]-changes[last]-1 <= 2*context {
+			last++
+		}
+		start := max(0, changes[i]-context)
+		end := min(len(ops), changes[last]+context+1)
+
+		hunk := diffHunk{
+			OldStart: oldAt[start] + 1,
+			OldLines: oldAt[end] - oldAt[start],
+			NewStart: newAt[start] + 1,
+			NewLines: newAt[end] - newAt[start],
+		}
+		// An empty range starts at the line before it, as in diff -u.
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+		for _, op := range ops[start:end] {
+			switch op.kind {
+			case diffEqual:
+				hunk.Lines = append(hunk.Lines, " "+op.newLine)
+			case diffDelete:
+				hunk.Lines = append(hunk.Lines, "-"+op.oldLine)
+			case diffInsert:
+				hunk.Lines = append(hunk.Lines, "+"+op.newLine)
+			}
+		}
+		hunks = append(hunks, hunk)
+		i = last + 1
+	}
+	return hunks
+}
+
+func (s *Service) colorEnabled(w io.Writer) bool {
+	switch s.config.Color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
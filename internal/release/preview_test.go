@@ -0,0 +1,175 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServicePreviewPrintsDiffAgainstOpenPullRequest(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+		releasePullRequests: []PullRequest{
+			{Number: 99, Title: "Release title", Body: "- [x] #7 @carol", Labels: []string{"release"}},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		Command:          CommandPreview,
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Title:            "Release title",
+		Labels:           []string{"release", "qa"},
+		ExtraReviewers:   []string{"dave"},
+		NoFetch:          true,
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("preview: %v", err)
+	}
+	if fakeGitHub.updateCalled || fakeGitHub.createCalls > 0 {
+		t.Fatalf("preview must not change the pull request")
+	}
+
+	want := "Release title\n\n- [x] #7 @carol\n- [ ] #1 @alice\n\n" +
+		"--- #99\n+++ preview\n" +
+		"@@ -1 +1,2 @@\n - [x] #7 @carol\n+- [ ] #1 @alice\n" +
+		"@@ labels @@\n release\n+qa\n" +
+		"@@ reviewers @@\n+dave\n"
+	if got := stdout.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestServiceRunDryRunIncludesPreviewInJSON(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+		},
+		releasePullRequests: []PullRequest{
+			{Number: 99, Title: "Old title", Body: "- [ ] #1 @alice", Assignees: []User{{LoginName: "alice"}}},
+		},
+	}
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Title:            "New title",
+		AssignPRAuthor:   true,
+		DryRun:           true,
+		JSON:             true,
+		NoFetch:          true,
+		Color:            ColorAlways,
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("dry-run: %v", err)
+	}
+	if fakeGitHub.updateCalled {
+		t.Fatalf("dry-run must not update the pull request")
+	}
+	if !strings.Contains(stderr.String(), colorRed+"-Old title"+colorReset) || !strings.Contains(stderr.String(), colorGreen+"+New title"+colorReset) {
+		t.Fatalf("expected a colored title diff on stderr: %q", stderr.String())
+	}
+
+	var payload struct {
+		Preview releasePreview `json:"preview"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	preview := payload.Preview
+	if preview.Number != 99 || preview.Create || !preview.Changed {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if preview.Title != (titleChange{Old: "Old title", New: "New title", Changed: true}) {
+		t.Fatalf("unexpected title change: %+v", preview.Title)
+	}
+	if len(preview.BodyHunks) != 0 {
+		t.Fatalf("expected no body hunks, got %+v", preview.BodyHunks)
+	}
+	if !reflect.DeepEqual(preview.Assignees, valuesChange{Current: []string{"alice"}, Added: []string{}}) {
+		t.Fatalf("unexpected assignees: %+v", preview.Assignees)
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	t.Parallel()
+
+	lines := func(s string) []string { return strings.Split(s, ",") }
+	tests := []struct {
+		name string
+		old  []string
+		new  []string
+		want []string
+	}{
+		{
+			name: "no changes",
+			old:  lines("a,b"),
+			new:  lines("a,b"),
+			want: nil,
+		},
+		{
+			name: "context is trimmed",
+			old:  lines("1,2,3,4,5,6,7,8"),
+			new:  lines("1,2,3,4,x,5,6,7,8"),
+			want: []string{"@@ -2,6 +2,7 @@  2  3  4 +x  5  6  7"},
+		},
+		{
+			name: "distant changes get separate hunks",
+			old:  lines("a,1,2,3,4,5,6,7,b"),
+			new:  lines("A,1,2,3,4,5,6,7,B"),
+			want: []string{
+				"@@ -1,4 +1,4 @@ -a +A  1  2  3",
+				"@@ -6,4 +6,4 @@  5  6  7 -b +B",
+			},
+		},
+		{
+			name: "close changes share a hunk",
+			old:  lines("a,1,2,3,b"),
+			new:  lines("A,1,2,3,B"),
+			want: []string{"@@ -1,5 +1,5 @@ -a +A  1  2  3 -b +B"},
+		},
+		{
+			name: "empty old side",
+			old:  nil,
+			new:  lines("a"),
+			want: []string{"@@ -0,0 +1 @@ +a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, hunk := range diffHunks(diffLines(tt.old, tt.new), diffContextLines) {
+				got = append(got, strings.Join(append([]string{hunk.header()}, hunk.Lines...), " "))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
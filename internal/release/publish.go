@@ -0,0 +1,244 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+var ErrNoMergedReleasePullRequest = errors.New("no merged release pull request")
+
+const DefaultReleaseNotesTemplate = `{{ .Tag }}
+{{- range .PullRequests }}
+- #{{ .Number }} {{ .Title }}{{ with .Mention }} {{ . }}{{ end }}
+{{- end }}
+`
+
+const DefaultTagPattern = "{{ .SuggestedVersion }}"
+
+// BuildReleaseNotes renders the release name and notes with the same data as
+// the release pull request template.
+func BuildReleaseNotes(repoRoot, templatePath string, input TemplateData) (string, string, error) {
+	return renderTitleAndBody(repoRoot, templatePath, DefaultReleaseNotesTemplate, input)
+}
+
+type publishResult struct {
+	Release            *Release           `json:"release"`
+	ReleasePullRequest *PullRequest       `json:"release_pull_request"`
+	MergedPullRequests []PullRequest      `json:"merged_pull_requests"`
+	SuggestedVersion   *VersionSuggestion `json:"suggested_version,omitempty"`
+}
+
+// publish tags the merge commit of the most recently merged release pull
+// request and creates or updates the matching release.
+func (s *Service) publish(ctx context.Context) error {
+	client, ok := s.github.(ReleaseClient)
+	if !ok {
+		return fmt.Errorf("publish is not supported for %s", s.config.Repository.ProviderName())
+	}
+
+	if !s.config.NoFetch {
+		if err := s.git.RemoteUpdate(ctx, s.config.RemoteName); err != nil {
+			return err
+		}
+	}
+
+	releasePR, err := s.latestMergedReleasePullRequest(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	pullRequests, err := s.github.GetPullRequests(ctx, checklistPullRequestNumbers(releasePR.Body, s.config.Repository))
+	if err != nil {
+		return err
+	}
+	mergedPRs := make([]PullRequest, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		if pr.Merged {
+			mergedPRs = append(mergedPRs, pr)
+		}
+	}
+
+	filter, err := s.config.pathFilter()
+	if err != nil {
+		return err
+	}
+	changedFiles, err := s.github.ListPullRequestFiles(ctx, releasePR.Number)
+	if err != nil {
+		return err
+	}
+
+	// Tags already on the merge commit come from an earlier run and must not
+	// bump the version again.
+	version, err := s.suggestVersion(ctx, releasePR.MergeCommitSHA+"^1", mergedPRs)
+	if err != nil {
+		return err
+	}
+
+	data := TemplateData{
+		ReleasePullRequest: releasePR,
+		MergedPullRequests: excludeLabeled(mergedPRs, s.config.ExcludeLabels),
+		ChangedFiles:       filter.files(changedFiles),
+		Mention:            s.config.Mention,
+		Categories:         s.config.Categories,
+		Version:            version,
+	}
+	data.Tag, err = renderTag(s.config.TagPattern, data)
+	if err != nil {
+		return err
+	}
+
+	root, err := s.git.Root(ctx)
+	if err != nil {
+		return err
+	}
+	name, body, err := BuildReleaseNotes(root, s.config.ReleaseTemplatePath, data)
+	if err != nil {
+		return err
+	}
+
+	release := Release{
+		TagName:         data.Tag,
+		TargetCommitish: releasePR.MergeCommitSHA,
+		Name:            name,
+		Body:            body,
+		Draft:           s.config.Draft,
+		Prerelease:      s.config.Prerelease,
+	}
+	result := publishResult{
+		Release:            &release,
+		ReleasePullRequest: releasePR,
+		MergedPullRequests: mergedPRs,
+		SuggestedVersion:   &version,
+	}
+
+	existing, err := client.FindRelease(ctx, release.TagName)
+	if err != nil {
+		return err
+	}
+
+	if s.config.DryRun {
+		action := "create"
+		if existing != nil {
+			action = "update"
+		}
+		s.say(fmt.Sprintf("Dry-run. Would %s release %s at %s", action, release.TagName, shortSHA(release.TargetCommitish)))
+		s.say(name)
+		s.say(body)
+		s.dumpPublishJSON(result)
+		s.reportPublishActions(result, stageStatusDryRun)
+		return nil
+	}
+
+	// Drafts get their tag from target_commitish when they are published.
+	if !release.Draft {
+		if err := s.ensureTag(ctx, client, release.TagName, release.TargetCommitish); err != nil {
+			return err
+		}
+	}
+
+	var published *Release
+	mode, verb := stageStatusCreated, "Created"
+	if existing != nil {
+		mode, verb = stageStatusUpdated, "Updated"
+		published, err = client.UpdateRelease(ctx, existing.ID, release)
+	} else {
+		published, err = client.CreateRelease(ctx, release)
+	}
+	if err != nil {
+		return err
+	}
+	s.say(fmt.Sprintf("%s release %s: %s", verb, published.TagName, published.URL))
+
+	result.Release = published
+	s.dumpPublishJSON(result)
+	s.reportPublishActions(result, mode)
+	return nil
+}
+
+func (s *Service) latestMergedReleasePullRequest(ctx context.Context, client ReleaseClient) (*PullRequest, error) {
+	candidates, err := client.ListMergedReleasePullRequests(
+		ctx,
+		s.config.Repository.HeadRef(s.config.StagingBranch),
+		s.config.ProductionBranch,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *PullRequest
+	for idx := range candidates {
+		if latest == nil || candidates[idx].MergedAt.After(latest.MergedAt) {
+			latest = &candidates[idx]
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("%w from %s to %s", ErrNoMergedReleasePullRequest, s.config.StagingBranch, s.config.ProductionBranch)
+	}
+	if latest.MergeCommitSHA == "" {
+		return nil, fmt.Errorf("release pull request #%d has no merge commit", latest.Number)
+	}
+	return latest, nil
+}
+
+func (s *Service) ensureTag(ctx context.Context, client ReleaseClient, tag, sha string) error {
+	current, err := client.GetTagSHA(ctx, tag)
+	if err != nil {
+		return err
+	}
+	switch current {
+	case "":
+		return client.CreateTag(ctx, tag, sha)
+	case sha:
+		return nil
+	default:
+		return fmt.Errorf("tag %s already points to %s, not %s", tag, shortSHA(current), shortSHA(sha))
+	}
+}
+
+func (s *Service) dumpPublishJSON(result publishResult) {
+	if !s.config.JSON {
+		return
+	}
+	encoder := json.NewEncoder(s.stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(result)
+}
+
+func renderTag(pattern string, input TemplateData) (string, error) {
+	if pattern == "" {
+		pattern = DefaultTagPattern
+	}
+	tmpl, err := template.New("tag").Funcs(sprig.FuncMap()).Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("parse tag pattern: %w", err)
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, makeTemplateData(input)); err != nil {
+		return "", fmt.Errorf("render tag pattern: %w", err)
+	}
+	tag := strings.TrimSpace(rendered.String())
+	if tag == "" {
+		return "", fmt.Errorf("tag pattern %q rendered an empty tag", pattern)
+	}
+	return tag, nil
+}
+
+// checklistPullRequestNumbers reads the pull request task items of a release
+// pull request body, skipping manual regions.
+func checklistPullRequestNumbers(body string, repository Repository) []int {
+	body, _ = extractManualRegions(body)
+	var numbers []int
+	for _, item := range parseTaskList(splitLines(body), repository) {
+		if item.task() && item.number > 0 {
+			numbers = append(numbers, item.number)
+		}
+	}
+	return uniqueInts(numbers)
+}
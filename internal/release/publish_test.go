@@ -0,0 +1,187 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeReleaseClient struct {
+	*fakeGitHubClient
+
+	mergedReleasePullRequests []PullRequest
+	tags                      map[string]string
+	releases                  []Release
+
+	createdTags     []string
+	createdReleases []Release
+	updatedReleases []Release
+}
+
+func (f *fakeReleaseClient) ListMergedReleasePullRequests(_ context.Context, head, base string) ([]PullRequest, error) {
+	return f.mergedReleasePullRequests, nil
+}
+
+func (f *fakeReleaseClient) GetTagSHA(_ context.Context, tag string) (string, error) {
+	return f.tags[tag], nil
+}
+
+func (f *fakeReleaseClient) CreateTag(_ context.Context, tag, sha string) error {
+	if f.tags == nil {
+		f.tags = map[string]string{}
+	}
+	f.tags[tag] = sha
+	f.createdTags = append(f.createdTags, tag)
+	return nil
+}
+
+func (f *fakeReleaseClient) FindRelease(_ context.Context, tag string) (*Release, error) {
+	for _, release := range f.releases {
+		if release.TagName == tag {
+			return &release, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeReleaseClient) CreateRelease(_ context.Context, release Release) (*Release, error) {
+	release.ID = int64(len(f.releases) + 1)
+	release.URL = "https://example.com/releases/" + release.TagName
+	f.releases = append(f.releases, release)
+	f.createdReleases = append(f.createdReleases, release)
+	return &release, nil
+}
+
+func (f *fakeReleaseClient) UpdateRelease(_ context.Context, id int64, release Release) (*Release, error) {
+	release.ID = id
+	f.updatedReleases = append(f.updatedReleases, release)
+	return &release, nil
+}
+
+func setupMergedReleasePullRequest(t *testing.T) (string, string) {
+	t.Helper()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "checkout", "master")
+	runGit(t, workDir, "tag", "v1.0.0")
+	runGit(t, workDir, "merge", "--no-ff", "origin/staging", "-m", "Merge pull request #50 from octo/staging")
+	runGit(t, workDir, "push", "origin", "master")
+	runGit(t, workDir, "fetch", "origin")
+	return workDir, runGit(t, workDir, "rev-parse", "HEAD")
+}
+
+func newPublishService(workDir string, client GitHubClient, config Config) *Service {
+	config.WorkDir = workDir
+	config.RemoteName = DefaultRemoteName
+	config.Repository = Repository{Owner: "octo", Name: "example", Scheme: "https"}
+	config.Token = "dummy"
+	config.ProductionBranch = "master"
+	config.StagingBranch = "staging"
+	config.Publish = true
+	config.NoFetch = true
+	return NewServiceWithClients(config, NewGit(workDir), client, &bytes.Buffer{}, &bytes.Buffer{})
+}
+
+func TestServicePublishCreatesTagAndRelease(t *testing.T) {
+	t.Parallel()
+
+	workDir, mergeSHA := setupMergedReleasePullRequest(t)
+	client := &fakeReleaseClient{
+		fakeGitHubClient: &fakeGitHubClient{
+			pullRequests: map[int]PullRequest{
+				1: {Number: 1, Title: "feat: add search", Merged: true, User: User{LoginName: "alice"}},
+				2: {Number: 2, Title: "fix: handle nil", Merged: true},
+			},
+		},
+		mergedReleasePullRequests: []PullRequest{
+			{Number: 40, Merged: true, MergedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), MergeCommitSHA: "0000000", Body: "- [x] #2"},
+			{Number: 50, Merged: true, MergedAt: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), MergeCommitSHA: mergeSHA, Body: "- [x] #1 @alice"},
+		},
+	}
+
+	service := newPublishService(workDir, client, Config{Mention: "author"})
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	if got := client.tags["v1.1.0"]; got != mergeSHA {
+		t.Fatalf("tag v1.1.0 points to %q, want %q (created %v)", got, mergeSHA, client.createdTags)
+	}
+	if len(client.createdReleases) != 1 {
+		t.Fatalf("got %d created releases, want 1", len(client.createdReleases))
+	}
+	release := client.createdReleases[0]
+	if release.Name != "v1.1.0" || release.TargetCommitish != mergeSHA {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+	if got, want := strings.TrimSpace(release.Body), "- #1 feat: add search @alice"; got != want {
+		t.Fatalf("got body %q, want %q", got, want)
+	}
+
+	// A rerun finds the tag on the merge commit and updates the same release.
+	runGit(t, workDir, "tag", "v1.1.0", mergeSHA)
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("publish again: %v", err)
+	}
+	if len(client.createdTags) != 1 || len(client.createdReleases) != 1 {
+		t.Fatalf("rerun created tags %v and %d releases", client.createdTags, len(client.createdReleases))
+	}
+	if len(client.updatedReleases) != 1 || client.updatedReleases[0].TagName != "v1.1.0" {
+		t.Fatalf("unexpected updated releases: %+v", client.updatedReleases)
+	}
+}
+
+func TestServicePublishDraftDryRunAndPattern(t *testing.T) {
+	t.Parallel()
+
+	workDir, mergeSHA := setupMergedReleasePullRequest(t)
+	client := &fakeReleaseClient{
+		fakeGitHubClient: &fakeGitHubClient{
+			pullRequests: map[int]PullRequest{1: {Number: 1, Title: "Add search", Merged: true}},
+		},
+		mergedReleasePullRequests: []PullRequest{
+			{Number: 50, Merged: true, MergeCommitSHA: mergeSHA, Body: "- [x] #1"},
+		},
+	}
+
+	service := newPublishService(workDir, client, Config{
+		DryRun:     true,
+		Draft:      true,
+		TagPattern: `release-{{ .ReleasePullRequest.Number }}`,
+	})
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("publish dry-run: %v", err)
+	}
+	if len(client.createdTags) != 0 || len(client.createdReleases) != 0 {
+		t.Fatalf("dry-run published tags %v and releases %+v", client.createdTags, client.createdReleases)
+	}
+
+	service.config.DryRun = false
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("publish draft: %v", err)
+	}
+	if len(client.createdTags) != 0 {
+		t.Fatalf("draft release should not create tags: %v", client.createdTags)
+	}
+	if len(client.createdReleases) != 1 || client.createdReleases[0].TagName != "release-50" || !client.createdReleases[0].Draft {
+		t.Fatalf("unexpected releases: %+v", client.createdReleases)
+	}
+}
+
+func TestServicePublishFailsWithoutMergedReleasePullRequest(t *testing.T) {
+	t.Parallel()
+
+	workDir, _ := setupMergedReleasePullRequest(t)
+	service := newPublishService(workDir, &fakeReleaseClient{fakeGitHubClient: &fakeGitHubClient{}}, Config{})
+	if err := service.Run(context.Background()); !errors.Is(err, ErrNoMergedReleasePullRequest) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service = newPublishService(workDir, &fakeGitHubClient{}, Config{})
+	if err := service.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
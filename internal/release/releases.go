@@ -0,0 +1,231 @@
+package release
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ReleaseClient publishes tags and releases. Only the GitHub clients
+// implement it.
+type ReleaseClient interface {
+	ListMergedReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error)
+	GetTagSHA(ctx context.Context, tag string) (string, error)
+	CreateTag(ctx context.Context, tag, sha string) error
+	FindRelease(ctx context.Context, tag string) (*Release, error)
+	CreateRelease(ctx context.Context, release Release) (*Release, error)
+	UpdateRelease(ctx context.Context, id int64, release Release) (*Release, error)
+}
+
+type Release struct {
+	ID              int64  `json:"id,omitempty"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+	URL             string `json:"url,omitempty"`
+}
+
+// ListMergedReleasePullRequests pages through the closed pull requests from
+// head to base, most recently updated first. Merging updates a pull request,
+// so once a page ends before the latest merge seen so far, no later page can
+// hold a more recent one.
+func (c *RESTGitHubClient) ListMergedReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error) {
+	const pageSize = 100
+	query := url.Values{}
+	query.Set("state", "closed")
+	query.Set("head", head)
+	query.Set("base", base)
+	query.Set("sort", "updated")
+	query.Set("direction", "desc")
+	query.Set("per_page", strconv.Itoa(pageSize))
+
+	var pullRequests []PullRequest
+	var latest time.Time
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		var response []pullRequestDTO
+		if err := c.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/pulls", c.repository.FullName()), query, nil, &response); err != nil {
+			return nil, err
+		}
+
+		for _, dto := range response {
+			pr := dto.toDomain()
+			// The pulls list leaves "merged" unset, so merged_at decides.
+			if pr.MergedAt.IsZero() {
+				continue
+			}
+			pr.Merged = true
+			pullRequests = append(pullRequests, pr)
+			if pr.MergedAt.After(latest) {
+				latest = pr.MergedAt
+			}
+		}
+
+		if len(response) < pageSize {
+			return pullRequests, nil
+		}
+		if last := response[len(response)-1].UpdatedAt; !latest.IsZero() && last != nil && last.Before(latest) {
+			return pullRequests, nil
+		}
+	}
+}
+
+// GetTagSHA returns the commit a tag points to, or "" when the tag does not
+// exist.
+func (c *RESTGitHubClient) GetTagSHA(ctx context.Context, tag string) (string, error) {
+	var response gitRefDTO
+	if err := c.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/ref/tags/%s", c.repository.FullName(), tag), nil, nil, &response); err != nil {
+		if isNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if response.Object.Type != "tag" {
+		return response.Object.SHA, nil
+	}
+
+	var annotated gitTagDTO
+	if err := c.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/git/tags/%s", c.repository.FullName(), response.Object.SHA), nil, nil, &annotated); err != nil {
+		return "", err
+	}
+	return annotated.Object.SHA, nil
+}
+
+func (c *RESTGitHubClient) CreateTag(ctx context.Context, tag, sha string) error {
+	request := map[string]string{
+		"ref": "refs/tags/" + tag,
+		"sha": sha,
+	}
+	return c.request(ctx, http.MethodPost, fmt.Sprintf("repos/%s/git/refs", c.repository.FullName()), nil, request, nil)
+}
+
+// FindRelease looks the release up in the release list because the
+// releases/tags endpoint does not return drafts.
+func (c *RESTGitHubClient) FindRelease(ctx context.Context, tag string) (*Release, error) {
+	const pageSize = 100
+
+	for page := 1; ; page++ {
+		query := url.Values{}
+		query.Set("per_page", fmt.Sprintf("%d", pageSize))
+		query.Set("page", fmt.Sprintf("%d", page))
+
+		var response []releaseDTO
+		if err := c.request(ctx, http.MethodGet, fmt.Sprintf("repos/%s/releases", c.repository.FullName()), query, nil, &response); err != nil {
+			return nil, err
+		}
+		for _, dto := range response {
+			if dto.TagName == tag {
+				release := dto.toDomain()
+				return &release, nil
+			}
+		}
+		if len(response) < pageSize {
+			return nil, nil
+		}
+	}
+}
+
+func (c *RESTGitHubClient) CreateRelease(ctx context.Context, release Release) (*Release, error) {
+	var response releaseDTO
+	if err := c.request(ctx, http.MethodPost, fmt.Sprintf("repos/%s/releases", c.repository.FullName()), nil, newReleaseRequest(release), &response); err != nil {
+		return nil, err
+	}
+	created := response.toDomain()
+	return &created, nil
+}
+
+func (c *RESTGitHubClient) UpdateRelease(ctx context.Context, id int64, release Release) (*Release, error) {
+	var response releaseDTO
+	if err := c.request(ctx, http.MethodPatch, fmt.Sprintf("repos/%s/releases/%d", c.repository.FullName(), id), nil, newReleaseRequest(release), &response); err != nil {
+		return nil, err
+	}
+	updated := response.toDomain()
+	return &updated, nil
+}
+
+func (c *GraphQLGitHubClient) ListMergedReleasePullRequests(ctx context.Context, head, base string) ([]PullRequest, error) {
+	return c.rest.ListMergedReleasePullRequests(ctx, head, base)
+}
+
+func (c *GraphQLGitHubClient) GetTagSHA(ctx context.Context, tag string) (string, error) {
+	return c.rest.GetTagSHA(ctx, tag)
+}
+
+func (c *GraphQLGitHubClient) CreateTag(ctx context.Context, tag, sha string) error {
+	return c.rest.CreateTag(ctx, tag, sha)
+}
+
+func (c *GraphQLGitHubClient) FindRelease(ctx context.Context, tag string) (*Release, error) {
+	return c.rest.FindRelease(ctx, tag)
+}
+
+func (c *GraphQLGitHubClient) CreateRelease(ctx context.Context, release Release) (*Release, error) {
+	return c.rest.CreateRelease(ctx, release)
+}
+
+func (c *GraphQLGitHubClient) UpdateRelease(ctx context.Context, id int64, release Release) (*Release, error) {
+	return c.rest.UpdateRelease(ctx, id, release)
+}
+
+type releaseRequest struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+func newReleaseRequest(release Release) releaseRequest {
+	return releaseRequest{
+		TagName:         release.TagName,
+		TargetCommitish: release.TargetCommitish,
+		Name:            release.Name,
+		Body:            release.Body,
+		Draft:           release.Draft,
+		Prerelease:      release.Prerelease,
+	}
+}
+
+type releaseDTO struct {
+	ID              int64  `json:"id"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+	HTMLURL         string `json:"html_url"`
+}
+
+func (r releaseDTO) toDomain() Release {
+	return Release{
+		ID:              r.ID,
+		TagName:         r.TagName,
+		TargetCommitish: r.TargetCommitish,
+		Name:            r.Name,
+		Body:            r.Body,
+		Draft:           r.Draft,
+		Prerelease:      r.Prerelease,
+		URL:             r.HTMLURL,
+	}
+}
+
+type gitRefDTO struct {
+	Object struct {
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	} `json:"object"`
+}
+
+type gitTagDTO struct {
+	Object struct {
+		SHA string `json:"sha"`
+	} `json:"object"`
+}
@@ -0,0 +1,162 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestRESTGitHubClientListMergedReleasePullRequestsSkipsClosedUnmerged(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.URL.Path != "/api/v3/repos/octo/example/pulls" || query.Get("state") != "closed" || query.Get("head") != "octo:staging" || query.Get("base") != "main" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`[
+			{"number": 3, "merged_at": "2026-02-01T00:00:00Z", "merge_commit_sha": "abc"},
+			{"number": 2, "merged_at": null}
+		]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	pullRequests, err := client.ListMergedReleasePullRequests(context.Background(), "octo:staging", "main")
+	if err != nil {
+		t.Fatalf("list merged release pull requests: %v", err)
+	}
+	if got, want := pullRequestNumbers(pullRequests), []int{3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if !pullRequests[0].Merged {
+		t.Fatalf("expected merged pull request")
+	}
+}
+
+func TestRESTGitHubClientListMergedReleasePullRequestsPaginates(t *testing.T) {
+	t.Parallel()
+
+	// Page 1 holds 100 recently commented, unmerged pull requests; the latest
+	// merge is on page 2, and page 3 ends before it so page 4 is not read.
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if query.Get("per_page") != "100" {
+			http.Error(w, "unexpected per_page", http.StatusBadRequest)
+			return
+		}
+		updated := func(day int) string {
+			return fmt.Sprintf("2026-03-%02dT00:00:00Z", day)
+		}
+		var response []map[string]any
+		switch query.Get("page") {
+		case "1":
+			for number := 400; number > 300; number-- {
+				response = append(response, map[string]any{"number": number, "updated_at": updated(20)})
+			}
+		case "2":
+			response = append(response, map[string]any{"number": 300, "merged_at": "2026-03-10T00:00:00Z", "updated_at": updated(10)})
+			for number := 299; number > 200; number-- {
+				response = append(response, map[string]any{"number": number, "updated_at": updated(10)})
+			}
+		case "3":
+			for number := 200; number > 100; number-- {
+				response = append(response, map[string]any{"number": number, "merged_at": "2026-02-01T00:00:00Z", "updated_at": updated(1)})
+			}
+		default:
+			http.Error(w, "page should not be read", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	pullRequests, err := client.ListMergedReleasePullRequests(context.Background(), "octo:staging", "main")
+	if err != nil {
+		t.Fatalf("list merged release pull requests: %v", err)
+	}
+	if len(pullRequests) != 101 || pullRequests[0].Number != 300 {
+		t.Fatalf("got %d pull requests starting at %v", len(pullRequests), pullRequestNumbers(pullRequests[:1]))
+	}
+	if requests != 3 {
+		t.Fatalf("got %d requests, want 3", requests)
+	}
+}
+
+func TestRESTGitHubClientGetTagSHAPeelsAnnotatedTags(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/octo/example/git/ref/tags/v1.0.0":
+			_, _ = w.Write([]byte(`{"object": {"type": "commit", "sha": "c1"}}`))
+		case "/api/v3/repos/octo/example/git/ref/tags/v1.1.0":
+			_, _ = w.Write([]byte(`{"object": {"type": "tag", "sha": "t2"}}`))
+		case "/api/v3/repos/octo/example/git/tags/t2":
+			_, _ = w.Write([]byte(`{"object": {"type": "commit", "sha": "c2"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	for tag, want := range map[string]string{"v1.0.0": "c1", "v1.1.0": "c2", "v9.9.9": ""} {
+		got, err := client.GetTagSHA(context.Background(), tag)
+		if err != nil {
+			t.Fatalf("get tag %s: %v", tag, err)
+		}
+		if got != want {
+			t.Fatalf("tag %s: got %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestRESTGitHubClientFindReleasePaginatesAndFindsDrafts(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/octo/example/releases" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var releases []releaseDTO
+		switch page {
+		case 1:
+			for idx := range 100 {
+				releases = append(releases, releaseDTO{ID: int64(idx), TagName: fmt.Sprintf("v0.%d.0", idx)})
+			}
+		case 2:
+			releases = append(releases, releaseDTO{ID: 500, TagName: "v2.0.0", Draft: true})
+		}
+		_ = json.NewEncoder(w).Encode(releases)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	release, err := client.FindRelease(context.Background(), "v2.0.0")
+	if err != nil {
+		t.Fatalf("find release: %v", err)
+	}
+	if release == nil || release.ID != 500 || !release.Draft {
+		t.Fatalf("unexpected release: %+v", release)
+	}
+
+	release, err = client.FindRelease(context.Background(), "v3.0.0")
+	if err != nil {
+		t.Fatalf("find missing release: %v", err)
+	}
+	if release != nil {
+		t.Fatalf("expected no release, got %+v", release)
+	}
+}
@@ -0,0 +1,158 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = time.Second
+	DefaultRetryMaxDelay  = 30 * time.Second
+
+	// GitHub asks clients to wait at least a minute after hitting a secondary
+	// rate limit without a Retry-After header.
+	secondaryRateLimitDelay = time.Minute
+)
+
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p == (RetryPolicy{}) {
+		return DefaultRetryPolicy()
+	}
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryBaseDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = max(p.BaseDelay, DefaultRetryMaxDelay)
+	}
+	return p
+}
+
+// backoff returns an exponential delay with equal jitter: half of the delay is
+// fixed and the other half is randomized so concurrent clients spread out.
+func (p RetryPolicy) backoff(attempt int, random func() float64) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	half := delay / 2
+	return half + time.Duration(random()*float64(delay-half))
+}
+
+type idempotentRequestKey struct{}
+
+// withIdempotentRequest marks a non-GET request as safe to replay, e.g. adding
+// labels, where sending the same body twice leaves the same state behind.
+func withIdempotentRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentRequestKey{}, true)
+}
+
+func isIdempotentRequest(ctx context.Context, method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	marked, _ := ctx.Value(idempotentRequestKey{}).(bool)
+	return marked
+}
+
+type retryDecision struct {
+	retry bool
+	wait  time.Duration
+}
+
+func (c *RESTGitHubClient) retryAfterTransportError(ctx context.Context, method string, err error, attempt int) retryDecision {
+	policy := c.retry.withDefaults()
+	if ctx.Err() != nil || attempt >= policy.MaxRetries {
+		return retryDecision{}
+	}
+	// A failed dial never reached the server, so even a create can be resent.
+	var opErr *net.OpError
+	if !isIdempotentRequest(ctx, method) && !(errors.As(err, &opErr) && opErr.Op == "dial") {
+		return retryDecision{}
+	}
+	return retryDecision{retry: true, wait: policy.backoff(attempt, c.random)}
+}
+
+func (c *RESTGitHubClient) retryAfterResponse(ctx context.Context, method string, resp *http.Response, payload []byte, attempt int) retryDecision {
+	policy := c.retry.withDefaults()
+	if attempt >= policy.MaxRetries {
+		return retryDecision{}
+	}
+
+	if wait, ok := c.rateLimitRetryDelay(resp); ok {
+		return retryDecision{retry: true, wait: wait}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || isSecondaryRateLimit(resp, payload) {
+		if wait, ok := retryAfterDelay(resp); ok {
+			return retryDecision{retry: true, wait: wait}
+		}
+		return retryDecision{retry: true, wait: max(secondaryRateLimitDelay, policy.backoff(attempt, c.random))}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if !isIdempotentRequest(ctx, method) {
+			return retryDecision{}
+		}
+		if wait, ok := retryAfterDelay(resp); ok {
+			return retryDecision{retry: true, wait: min(wait, policy.MaxDelay)}
+		}
+		return retryDecision{retry: true, wait: policy.backoff(attempt, c.random)}
+	}
+
+	return retryDecision{}
+}
+
+func isSecondaryRateLimit(resp *http.Response, payload []byte) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	message := strings.ToLower(string(payload))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse detection")
+}
+
+func retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	retryAfter := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if retryAfter == "" {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(retryAfter)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func (c *RESTGitHubClient) random() float64 {
+	if c.randFn != nil {
+		return c.randFn()
+	}
+	return rand.Float64()
+}
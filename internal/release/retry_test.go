@@ -0,0 +1,204 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRESTGitHubClientRetriesTransientServerErrors(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		switch requests {
+		case 1:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "2")
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 7})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var sleeps []time.Duration
+	client := newTestGitHubClient(server)
+	client.randFn = func() float64 { return 1 }
+	client.sleepFn = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	pr, err := client.getPullRequest(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("get pull request: %v", err)
+	}
+	if pr.Number != 7 {
+		t.Fatalf("unexpected pull request: %+v", pr)
+	}
+	if requests != 3 {
+		t.Fatalf("got %d requests, want 3", requests)
+	}
+	if len(sleeps) != 2 || sleeps[0] != time.Second || sleeps[1] != 2*time.Second {
+		t.Fatalf("unexpected backoff: %v", sleeps)
+	}
+}
+
+func TestRESTGitHubClientDoesNotRetryCreatePullRequestOnServerError(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	_, err := client.CreatePullRequest(context.Background(), "title", "octo:staging", "master", "")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("got %d requests, want 1", requests)
+	}
+}
+
+func TestRESTGitHubClientRetriesIdempotentPostOnServerError(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	if err := client.AddLabels(context.Background(), 7, []string{"release"}); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+}
+
+func TestRESTGitHubClientRetriesSecondaryRateLimitForAnyMethod(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, `{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again."}`, http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 100})
+	}))
+	t.Cleanup(server.Close)
+
+	var sleeps []time.Duration
+	client := newTestGitHubClient(server)
+	client.sleepFn = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	pr, err := client.CreatePullRequest(context.Background(), "title", "octo:staging", "master", "")
+	if err != nil {
+		t.Fatalf("create pull request: %v", err)
+	}
+	if pr.Number != 100 {
+		t.Fatalf("unexpected pull request: %+v", pr)
+	}
+	if len(sleeps) != 1 || sleeps[0] < secondaryRateLimitDelay {
+		t.Fatalf("unexpected secondary rate limit wait: %v", sleeps)
+	}
+}
+
+func TestRESTGitHubClientRetriesDroppedConnectionsForGet(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("hijack: %v", err)
+				return
+			}
+			conn.Close()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pullRequestDTO{Number: 7})
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	if _, err := client.getPullRequest(context.Background(), 7); err != nil {
+		t.Fatalf("get pull request: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+}
+
+func TestRESTGitHubClientHonorsMaxRetries(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	client := newTestGitHubClient(server)
+	client.retry = RetryPolicy{MaxRetries: 1, BaseDelay: time.Second, MaxDelay: time.Second}
+	if _, err := client.getPullRequest(context.Background(), 7); err == nil {
+		t.Fatalf("expected error after exhausting retries")
+	}
+	if requests != 2 {
+		t.Fatalf("got %d requests, want 2", requests)
+	}
+}
+
+func TestRetryPolicyBackoffIsCappedAndJittered(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 8 * time.Second}
+	tests := []struct {
+		attempt int
+		random  float64
+		want    time.Duration
+	}{
+		{attempt: 0, random: 0, want: 500 * time.Millisecond},
+		{attempt: 0, random: 1, want: time.Second},
+		{attempt: 2, random: 0.5, want: 3 * time.Second},
+		{attempt: 9, random: 1, want: 8 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.backoff(tt.attempt, func() float64 { return tt.random }); got != tt.want {
+			t.Fatalf("backoff(%d, %v) = %s, want %s", tt.attempt, tt.random, got, tt.want)
+		}
+	}
+}
@@ -6,26 +6,39 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 var ErrNoPullRequestsToRelease = errors.New("no pull requests to be released")
 
+const commitLookupConcurrency = 8
+
 type Service struct {
 	config Config
-	git    *Git
+	git    GitBackend
 	github GitHubClient
 	stdout io.Writer
 	stderr io.Writer
 }
 
 func NewService(config Config, stdout, stderr io.Writer) *Service {
-	return NewServiceWithClients(config, NewGit(config.WorkDir), NewRESTGitHubClient(config), stdout, stderr)
+	git := NewGitBackend(config)
+	github := NewGitHubClient(config)
+	// The GitHub clients also resolve GitHub App installation tokens.
+	if goGit, ok := git.(*GoGit); ok {
+		if tokens, ok := github.(tokenSource); ok {
+			goGit.tokens = tokens
+		}
+	}
+	return NewServiceWithClients(config, git, github, stdout, stderr)
 }
 
-func NewServiceWithClients(config Config, git *Git, github GitHubClient, stdout, stderr io.Writer) *Service {
+func NewServiceWithClients(config Config, git GitBackend, github GitHubClient, stdout, stderr io.Writer) *Service {
 	return &Service{
 		config: config,
 		git:    git,
@@ -36,23 +49,82 @@ func NewServiceWithClients(config Config, git *Git, github GitHubClient, stdout,
 }
 
 func (s *Service) Run(ctx context.Context) error {
-	mergedPRs, err := s.fetchMergedPullRequests(ctx)
+	defer s.reportCacheStats()
+
+	switch s.config.Command {
+	case CommandPreview:
+		return s.preview(ctx)
+	case CommandStatus:
+		return s.status(ctx)
+	case CommandDoctor:
+		return s.doctor(ctx)
+	}
+
+	if s.config.Publish {
+		return s.publish(ctx)
+	}
+	if s.config.Changelog {
+		return s.updateChangelog(ctx)
+	}
+	if s.config.Check || s.config.Finalize {
+		return s.finalize(ctx)
+	}
+	if len(s.config.Stages) > 0 {
+		return s.runStages(ctx)
+	}
+
+	result, err := s.runStage(ctx)
+	if err != nil && !errors.Is(err, ErrNoPullRequestsToRelease) {
+		result.Status = stageStatusFailed
+		result.Error = err.Error()
+	}
+	s.reportActions([]stageResult{result}, false)
 	if err != nil {
 		return err
 	}
+	if s.config.JSON {
+		s.dumpJSON(result)
+	}
+	return nil
+}
+
+func (s *Service) runStage(ctx context.Context) (stageResult, error) {
+	result := stageResult{
+		Name:             s.config.stageName(),
+		StagingBranch:    s.config.StagingBranch,
+		ProductionBranch: s.config.ProductionBranch,
+	}
+
+	filter, err := s.config.pathFilter()
+	if err != nil {
+		return result, err
+	}
+
+	mergedPRs, err := s.fetchMergedPullRequests(ctx, filter)
+	if err != nil {
+		return result, err
+	}
 	if len(mergedPRs) == 0 {
 		s.say("No pull requests to be released")
-		return ErrNoPullRequestsToRelease
+		result.Status = stageStatusNoPullRequests
+		return result, ErrNoPullRequestsToRelease
+	}
+	result.MergedPullRequests = mergedPRs
+
+	version, err := s.suggestVersion(ctx, s.config.remoteBranch(s.config.ProductionBranch), mergedPRs)
+	if err != nil {
+		return result, err
 	}
+	result.SuggestedVersion = &version
 
 	root, err := s.git.Root(ctx)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	existingPR, err := s.detectExistingReleasePullRequest(ctx)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	createMode := existingPR == nil
@@ -69,76 +141,61 @@ func (s *Service) Run(ctx context.Context) error {
 			"",
 		)
 		if err != nil {
-			return err
+			// The create may have reached the server even though the response
+			// was lost; reuse that pull request instead of failing the run.
+			recovered, detectErr := s.detectExistingReleasePullRequest(ctx)
+			if detectErr != nil || recovered == nil {
+				return result, err
+			}
+			s.warn(fmt.Sprintf("Create pull request failed (%v); reusing #%d", err, recovered.Number))
+			existingPR = recovered
 		}
 		changedFiles, err = s.github.ListPullRequestFiles(ctx, existingPR.Number)
 		if err != nil {
-			return err
+			return result, err
 		}
 	default:
 		changedFiles, err = s.github.ListPullRequestFiles(ctx, existingPR.Number)
 		if err != nil {
-			return err
+			return result, err
 		}
 	}
 
-	title, body, err := BuildTitleAndBody(
-		root,
-		existingPR,
-		mergedPRs,
-		changedFiles,
-		s.config.TemplatePath,
-		s.config.Mention,
-	)
-	if err != nil {
-		return err
-	}
+	changedFiles = filter.files(changedFiles)
 
-	if s.config.Title != "" {
-		title = s.config.Title
-	}
-
-	oldBody := ""
-	if existingPR != nil {
-		oldBody = existingPR.Body
-	}
-	if !s.config.OverwriteDescription {
-		body = MergeBodies(oldBody, body)
+	title, body, err := s.renderReleasePullRequest(root, existingPR, mergedPRs, changedFiles, version)
+	if err != nil {
+		return result, err
 	}
 
 	if s.config.DryRun {
 		s.say("Dry-run. Not updating PR")
-		s.say(title)
-		s.say(body)
-		if s.config.JSON {
-			s.dumpJSON(existingPR, mergedPRs, changedFiles)
-		}
-		return nil
+		preview := s.previewReleasePullRequest(existingPR, mergedPRs, title, body)
+		preview.write(s.stderr, s.colorEnabled(s.stderr))
+		result.Status = stageStatusDryRun
+		result.ReleasePullRequest = existingPR
+		result.ChangedFiles = changedFiles
+		result.Preview = &preview
+		return result, nil
 	}
 
 	releasePR, err := s.github.UpdatePullRequest(ctx, existingPR.Number, title, body)
 	if err != nil {
-		return err
+		return result, err
 	}
 
 	if err := s.github.AddLabels(ctx, releasePR.Number, s.config.Labels); err != nil {
-		return err
+		return result, err
 	}
 
 	if s.config.AssignPRAuthor {
-		assignees := collectMentionTargets(mergedPRs, s.config.Mention)
-		if err := s.github.AddAssignees(ctx, releasePR.Number, assignees); err != nil {
-			return err
+		if err := s.github.AddAssignees(ctx, releasePR.Number, s.releaseAssignees(mergedPRs)); err != nil {
+			return result, err
 		}
 	}
 
-	reviewers := append([]string(nil), s.config.ExtraReviewers...)
-	if s.config.RequestPRAuthorReview {
-		reviewers = append(reviewers, collectMentionTargets(mergedPRs, s.config.Mention)...)
-	}
-	reviewers = uniqueStrings(reviewers)
-	if err := s.github.RequestReviewers(ctx, releasePR.Number, reviewers); err != nil {
-		return err
+	if err := s.github.RequestReviewers(ctx, releasePR.Number, s.releaseReviewers(mergedPRs)); err != nil {
+		return result, err
 	}
 
 	mode := "Updated"
@@ -146,14 +203,63 @@ func (s *Service) Run(ctx context.Context) error {
 		mode = "Created"
 	}
 	s.say(fmt.Sprintf("%s pull request: %s", mode, releasePR.URL))
-	if s.config.JSON {
-		s.dumpJSON(releasePR, mergedPRs, changedFiles)
+
+	result.Status = stageStatusUpdated
+	if createMode {
+		result.Status = stageStatusCreated
 	}
+	result.ReleasePullRequest = releasePR
+	result.ChangedFiles = changedFiles
+	return result, nil
+}
 
-	return nil
+func (s *Service) releaseAssignees(mergedPRs []PullRequest) []string {
+	if !s.config.AssignPRAuthor {
+		return nil
+	}
+	return collectMentionTargets(mergedPRs, s.config.Mention)
 }
 
-func (s *Service) fetchMergedPullRequests(ctx context.Context) ([]PullRequest, error) {
+func (s *Service) releaseReviewers(mergedPRs []PullRequest) []string {
+	reviewers := append([]string(nil), s.config.ExtraReviewers...)
+	if s.config.RequestPRAuthorReview {
+		reviewers = append(reviewers, collectMentionTargets(mergedPRs, s.config.Mention)...)
+	}
+	return uniqueStrings(reviewers)
+}
+
+// renderReleasePullRequest renders the title and body and merges the body
+// with the one on existingPR.
+func (s *Service) renderReleasePullRequest(root string, existingPR *PullRequest, mergedPRs []PullRequest, changedFiles []ChangedFile, version VersionSuggestion) (string, string, error) {
+	title, body, err := BuildTitleAndBody(root, s.config.TemplatePath, TemplateData{
+		ReleasePullRequest: existingPR,
+		MergedPullRequests: excludeLabeled(mergedPRs, s.config.ExcludeLabels),
+		ChangedFiles:       changedFiles,
+		Mention:            s.config.Mention,
+		Categories:         s.config.Categories,
+		Version:            version,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if s.config.Title != "" {
+		title = s.config.Title
+	}
+
+	oldBody := ""
+	if existingPR != nil {
+		oldBody = existingPR.Body
+	}
+	if !s.config.OverwriteDescription {
+		body = MergeBodies(oldBody, body, s.config.Repository)
+	}
+	return title, body, nil
+}
+
+// fetchMergedPullRequests lists the pull requests to release, limited to
+// those touching filter.
+func (s *Service) fetchMergedPullRequests(ctx context.Context, filter pathFilter) ([]PullRequest, error) {
 	isShallow, err := s.git.IsShallow(ctx)
 	if err != nil {
 		return nil, err
@@ -173,6 +279,35 @@ func (s *Service) fetchMergedPullRequests(ctx context.Context) ([]PullRequest, e
 	if err != nil {
 		return nil, err
 	}
+
+	var mergedPullRequests []PullRequest
+	if s.config.PullRequestLookup == PullRequestLookupCommits {
+		mergedPullRequests, err = s.fetchMergedPullRequestsByCommits(ctx, numbers)
+	} else {
+		mergedPullRequests, err = s.fetchMergedPullRequestsByNumbers(ctx, numbers)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.Rebased {
+		rebased, err := s.fetchRebaseMergedPullRequests(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mergedPullRequests = mergePullRequestLists(mergedPullRequests, rebased)
+	}
+
+	if filter.active() {
+		return s.filterPullRequestsByPaths(ctx, filter, mergedPullRequests)
+	}
+	return mergedPullRequests, nil
+}
+
+func (s *Service) fetchMergedPullRequestsByNumbers(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	if s.config.Squashed && s.config.SquashDetection == SquashDetectionSubject {
+		return s.fetchMergedPullRequestsBySubjects(ctx, numbers)
+	}
 	if s.config.Squashed {
 		squashNumbers, squashErr := s.fetchSquashMergedPullRequests(ctx)
 		if squashErr != nil {
@@ -200,11 +335,234 @@ func (s *Service) fetchMergedPullRequests(ctx context.Context) ([]PullRequest, e
 	return mergedPullRequests, nil
 }
 
+// fetchMergedPullRequestsBySubjects takes PR numbers from "Title (#123)" and
+// "Merge pull request #123 from ..." subjects, confirms each candidate by its
+// merge commit SHA and only searches for commits the subjects do not explain.
+func (s *Service) fetchMergedPullRequestsBySubjects(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	commits, err := s.git.FirstParentCommits(ctx, s.config.RemoteName, s.config.ProductionBranch, s.config.StagingBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Several commits can name the same number, e.g. a squash and its revert.
+	candidates := map[int][]Commit{}
+	var unnumbered []Commit
+	for _, commit := range commits {
+		if number, ok := parseSubjectPullRequestNumber(commit); ok {
+			candidates[number] = append(candidates[number], commit)
+			continue
+		}
+		if !commit.Merge {
+			unnumbered = append(unnumbered, commit)
+		}
+	}
+
+	known := map[int]bool{}
+	for _, number := range numbers {
+		known[number] = true
+	}
+	lookup := append([]int(nil), numbers...)
+	for number := range candidates {
+		lookup = append(lookup, number)
+	}
+	lookup = uniqueInts(lookup)
+	sort.Ints(lookup)
+
+	pullRequests, err := s.github.GetPullRequests(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers of issues or of other repositories' pull requests are not
+	// returned; their commits are searched like unnumbered ones.
+	found := map[int]PullRequest{}
+	matched := map[string]bool{}
+	for _, pr := range pullRequests {
+		if !pr.Merged {
+			continue
+		}
+		if known[pr.Number] {
+			found[pr.Number] = pr
+		}
+		for _, commit := range candidates[pr.Number] {
+			if pr.MergeCommitSHA == commit.SHA {
+				found[pr.Number] = pr
+				matched[commit.SHA] = true
+			}
+		}
+	}
+	for _, numbered := range candidates {
+		for _, commit := range numbered {
+			if !matched[commit.SHA] && !commit.Merge {
+				unnumbered = append(unnumbered, commit)
+			}
+		}
+	}
+
+	shas := make([]string, 0, len(unnumbered))
+	for _, commit := range unnumbered {
+		shas = append(shas, shortSHA(commit.SHA))
+	}
+	sort.Strings(shas)
+	searched, err := s.searchPullRequestNumbers(ctx, shas)
+	if err != nil {
+		return nil, err
+	}
+	var missing []int
+	for _, number := range uniqueInts(searched) {
+		if _, ok := found[number]; !ok {
+			missing = append(missing, number)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Ints(missing)
+		fallback, err := s.github.GetPullRequests(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range fallback {
+			if pr.Merged {
+				found[pr.Number] = pr
+			}
+		}
+	}
+
+	mergedPullRequests := make([]PullRequest, 0, len(found))
+	for _, pr := range found {
+		mergedPullRequests = append(mergedPullRequests, pr)
+	}
+	sort.Slice(mergedPullRequests, func(i, j int) bool {
+		return mergedPullRequests[i].Number < mergedPullRequests[j].Number
+	})
+	return mergedPullRequests, nil
+}
+
+func (s *Service) fetchMergedPullRequestsByCommits(ctx context.Context, numbers []int) ([]PullRequest, error) {
+	mergeSHAs, err := s.git.MergeCommitSHAs(ctx, s.config.RemoteName, s.config.ProductionBranch, s.config.StagingBranch)
+	if err != nil {
+		return nil, err
+	}
+	var squashSHAs []string
+	if s.config.Squashed {
+		squashSHAs, err = s.git.FirstParentCommitSHAs(ctx, s.config.RemoteName, s.config.ProductionBranch, s.config.StagingBranch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shas := append(append([]string(nil), mergeSHAs...), squashSHAs...)
+	associated, err := s.lookupPullRequestsByCommits(ctx, shas)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[int]PullRequest{}
+	var unresolvedSquashSHAs []string
+	for idx, sha := range shas {
+		resolved := false
+		for _, pr := range associated[idx] {
+			if !pr.Merged || pr.MergeCommitSHA != sha {
+				continue
+			}
+			found[pr.Number] = pr
+			resolved = true
+		}
+		if !resolved && idx >= len(mergeSHAs) {
+			unresolvedSquashSHAs = append(unresolvedSquashSHAs, shortSHA(sha))
+		}
+	}
+
+	var missing []int
+	for _, number := range numbers {
+		if _, ok := found[number]; !ok {
+			missing = append(missing, number)
+		}
+	}
+	searched, err := s.searchPullRequestNumbers(ctx, unresolvedSquashSHAs)
+	if err != nil {
+		return nil, err
+	}
+	for _, number := range searched {
+		if _, ok := found[number]; !ok {
+			missing = append(missing, number)
+		}
+	}
+
+	missing = uniqueInts(missing)
+	if len(missing) > 0 {
+		sort.Ints(missing)
+		fallback, err := s.github.GetPullRequests(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range fallback {
+			if !pr.Merged {
+				continue
+			}
+			found[pr.Number] = pr
+		}
+	}
+
+	mergedPullRequests := make([]PullRequest, 0, len(found))
+	for _, pr := range found {
+		mergedPullRequests = append(mergedPullRequests, pr)
+	}
+	sort.Slice(mergedPullRequests, func(i, j int) bool {
+		return mergedPullRequests[i].Number < mergedPullRequests[j].Number
+	})
+	return mergedPullRequests, nil
+}
+
+// fetchRebaseMergedPullRequests finds pull requests merged with "Rebase and
+// merge", which leave neither a merge commit nor a PR number in the subject,
+// by asking which pull request each first-parent commit belongs to.
+func (s *Service) fetchRebaseMergedPullRequests(ctx context.Context) ([]PullRequest, error) {
+	shas, err := s.git.FirstParentCommitSHAs(ctx, s.config.RemoteName, s.config.ProductionBranch, s.config.StagingBranch)
+	if err != nil {
+		return nil, err
+	}
+	associated, err := s.lookupPullRequestsByCommits(ctx, shas)
+	if err != nil {
+		return nil, err
+	}
+
+	var pullRequests []PullRequest
+	for _, candidates := range associated {
+		for _, pr := range candidates {
+			if !pr.Merged || pr.BaseRef != s.config.StagingBranch {
+				continue
+			}
+			pullRequests = append(pullRequests, pr)
+		}
+	}
+	return mergePullRequestLists(nil, pullRequests), nil
+}
+
+func (s *Service) lookupPullRequestsByCommits(ctx context.Context, shas []string) ([][]PullRequest, error) {
+	results := make([][]PullRequest, len(shas))
+	err := forEachConcurrently(ctx, len(shas), commitLookupConcurrency, func(ctx context.Context, idx int) error {
+		pullRequests, err := s.github.ListPullRequestsForCommit(ctx, shas[idx])
+		if err != nil {
+			return err
+		}
+		results[idx] = pullRequests
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func (s *Service) fetchSquashMergedPullRequests(ctx context.Context) ([]int, error) {
 	shas, err := s.git.SquashCommitSHAs(ctx, s.config.RemoteName, s.config.ProductionBranch, s.config.StagingBranch)
 	if err != nil {
 		return nil, err
 	}
+	return s.searchPullRequestNumbers(ctx, shas)
+}
+
+func (s *Service) searchPullRequestNumbers(ctx context.Context, shas []string) ([]int, error) {
 	var numbers []int
 	for _, query := range buildSearchQueries(s.config.Repository.FullName(), shas) {
 		found, err := s.github.SearchPullRequestNumbers(ctx, query)
@@ -231,15 +589,19 @@ func (s *Service) detectExistingReleasePullRequest(ctx context.Context) (*PullRe
 	return &pullRequests[0], nil
 }
 
-func (s *Service) dumpJSON(releasePR *PullRequest, mergedPRs []PullRequest, changedFiles []ChangedFile) {
+func (s *Service) dumpJSON(result stageResult) {
 	payload := struct {
-		ReleasePullRequest *PullRequest  `json:"release_pull_request"`
-		MergedPullRequests []PullRequest `json:"merged_pull_requests"`
-		ChangedFiles       []ChangedFile `json:"changed_files"`
+		ReleasePullRequest *PullRequest       `json:"release_pull_request"`
+		MergedPullRequests []PullRequest      `json:"merged_pull_requests"`
+		ChangedFiles       []ChangedFile      `json:"changed_files"`
+		SuggestedVersion   *VersionSuggestion `json:"suggested_version,omitempty"`
+		Preview            *releasePreview    `json:"preview,omitempty"`
 	}{
-		ReleasePullRequest: releasePR,
-		MergedPullRequests: mergedPRs,
-		ChangedFiles:       changedFiles,
+		ReleasePullRequest: result.ReleasePullRequest,
+		MergedPullRequests: result.MergedPullRequests,
+		ChangedFiles:       result.ChangedFiles,
+		SuggestedVersion:   result.SuggestedVersion,
+		Preview:            result.Preview,
 	}
 
 	encoder := json.NewEncoder(s.stdout)
@@ -247,6 +609,21 @@ func (s *Service) dumpJSON(releasePR *PullRequest, mergedPRs []PullRequest, chan
 	_ = encoder.Encode(payload)
 }
 
+func (s *Service) reportCacheStats() {
+	if !s.config.Verbose {
+		return
+	}
+	reporter, ok := s.github.(interface{ CacheStats() CacheStats })
+	if !ok {
+		return
+	}
+	stats := reporter.CacheStats()
+	if stats.Hits == 0 && stats.Misses == 0 {
+		return
+	}
+	s.say(fmt.Sprintf("HTTP cache: %d hits, %d misses", stats.Hits, stats.Misses))
+}
+
 func (s *Service) say(message string) {
 	if strings.TrimSpace(message) == "" {
 		return
@@ -254,6 +631,22 @@ func (s *Service) say(message string) {
 	fmt.Fprintln(s.stderr, message)
 }
 
+func mergePullRequestLists(base []PullRequest, additional []PullRequest) []PullRequest {
+	seen := make(map[int]struct{}, len(base)+len(additional))
+	merged := make([]PullRequest, 0, len(base)+len(additional))
+	for _, pr := range append(append([]PullRequest(nil), base...), additional...) {
+		if _, ok := seen[pr.Number]; ok {
+			continue
+		}
+		seen[pr.Number] = struct{}{}
+		merged = append(merged, pr)
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Number < merged[j].Number
+	})
+	return merged
+}
+
 func collectMentionTargets(prs []PullRequest, mentionType string) []string {
 	targets := make([]string, 0, len(prs))
 	for _, pr := range prs {
@@ -296,6 +689,76 @@ func uniqueInts(values []int) []int {
 	return result
 }
 
+func forEachConcurrently(ctx context.Context, count, limit int, fn func(context.Context, int) error) error {
+	if count == 0 {
+		return nil
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, count)
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for idx := 0; idx < count; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				errs[idx] = ctx.Err()
+				return
+			}
+			defer func() { <-semaphore }()
+
+			if err := fn(ctx, idx); err != nil {
+				errs[idx] = err
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	squashSubjectPattern = regexp.MustCompile(`\(#(\d+)\)\s*$`)
+	mergeSubjectPattern  = regexp.MustCompile(`^Merge pull request #(\d+) from `)
+)
+
+func parseSubjectPullRequestNumber(commit Commit) (int, bool) {
+	pattern := squashSubjectPattern
+	if commit.Merge {
+		pattern = mergeSubjectPattern
+	}
+	match := pattern.FindStringSubmatch(commit.Subject)
+	if match == nil {
+		return 0, false
+	}
+	number, err := strconv.Atoi(match[1])
+	if err != nil {
+		return 0, false
+	}
+	return number, true
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
 func buildSearchQueries(repository string, shas []string) []string {
 	if len(shas) == 0 {
 		return nil
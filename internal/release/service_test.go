@@ -3,8 +3,11 @@ package release
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -52,7 +55,7 @@ func TestServiceFetchMergedPullRequestsIncludesAllChainedPullRequests(t *testing
 		StagingBranch:    "staging",
 	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
 
-	pullRequests, err := service.fetchMergedPullRequests(context.Background())
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
 	if err != nil {
 		t.Fatalf("fetch merged pull requests: %v", err)
 	}
@@ -84,7 +87,7 @@ func TestServiceFetchMergedPullRequestsExcludesUnrelatedPullRequests(t *testing.
 		StagingBranch:    "staging",
 	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
 
-	pullRequests, err := service.fetchMergedPullRequests(context.Background())
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
 	if err != nil {
 		t.Fatalf("fetch merged pull requests: %v", err)
 	}
@@ -95,6 +98,196 @@ func TestServiceFetchMergedPullRequestsExcludesUnrelatedPullRequests(t *testing.
 	}
 }
 
+func TestServiceFetchMergedPullRequestsResolvesMergeCommitsToPullRequests(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	firstMerge := runGit(t, workDir, "rev-parse", "origin/staging^1")
+	secondMerge := runGit(t, workDir, "rev-parse", "origin/staging")
+	fakeGitHub := &fakeGitHubClient{
+		commitPullRequests: map[string][]PullRequest{
+			firstMerge: {
+				{Number: 1, Title: "feature1", Merged: true, MergeCommitSHA: firstMerge},
+				{Number: 99, Title: "Release", State: "open"},
+			},
+			secondMerge: {
+				{Number: 2, Title: "feature2", Merged: true, MergeCommitSHA: "merged-into-master"},
+			},
+		},
+	}
+
+	service := NewServiceWithClients(Config{
+		WorkDir:           workDir,
+		RemoteName:        DefaultRemoteName,
+		Repository:        Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:             "dummy",
+		ProductionBranch:  "master",
+		StagingBranch:     "staging",
+		PullRequestLookup: PullRequestLookupCommits,
+	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
+
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
+	if err != nil {
+		t.Fatalf("fetch merged pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(fakeGitHub.getPullRequestCalls) != 0 {
+		t.Fatalf("expected no fallback lookups, got %v", fakeGitHub.getPullRequestCalls)
+	}
+	if got := len(fakeGitHub.commitLookups); got != 2 {
+		t.Fatalf("got %d commit lookups, want 2", got)
+	}
+}
+
+func TestServiceFetchMergedPullRequestsIncludesRebaseMergedPullRequests(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "checkout", "staging")
+	writeFile(t, filepath.Join(workDir, "rebased1.txt"), "rebased1\n")
+	runGit(t, workDir, "add", "rebased1.txt")
+	runGit(t, workDir, "commit", "-m", "rebased commit 1")
+	firstRebased := runGit(t, workDir, "rev-parse", "HEAD")
+	writeFile(t, filepath.Join(workDir, "rebased2.txt"), "rebased2\n")
+	runGit(t, workDir, "add", "rebased2.txt")
+	runGit(t, workDir, "commit", "-m", "rebased commit 2")
+	secondRebased := runGit(t, workDir, "rev-parse", "HEAD")
+	runGit(t, workDir, "push", "origin", "staging")
+	runGit(t, workDir, "fetch", "origin")
+
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "feature1", Merged: true},
+		},
+		commitPullRequests: map[string][]PullRequest{
+			firstRebased: {
+				{Number: 5, Title: "rebased", Merged: true, BaseRef: "staging"},
+			},
+			secondRebased: {
+				{Number: 5, Title: "rebased", Merged: true, BaseRef: "staging"},
+				{Number: 1, Title: "feature1", Merged: true, BaseRef: "staging"},
+				{Number: 9, Title: "other base", Merged: true, BaseRef: "develop"},
+				{Number: 10, Title: "still open", BaseRef: "staging"},
+			},
+		},
+	}
+
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Rebased:          true,
+	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
+
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
+	if err != nil {
+		t.Fatalf("fetch merged pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{1, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestServiceFetchMergedPullRequestsDetectsSquashesFromSubjects(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	runGit(t, workDir, "checkout", "staging")
+	commit := func(name, subject string) string {
+		writeFile(t, filepath.Join(workDir, name), name+"\n")
+		runGit(t, workDir, "add", name)
+		runGit(t, workDir, "commit", "-m", subject)
+		return runGit(t, workDir, "rev-parse", "HEAD")
+	}
+	squashed := commit("feature3.txt", "feature3 (#3)")
+	followUp := commit("feature3-docs.txt", "document feature3 (#3)")
+	mismatched := commit("feature4.txt", "revert of something (#4)")
+	unnumbered := commit("feature5.txt", "feature5")
+	issue := commit("feature6.txt", "fix crash reported in (#45)")
+	runGit(t, workDir, "push", "origin", "staging")
+	runGit(t, workDir, "fetch", "origin")
+
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "feature1", Merged: true},
+			3: {Number: 3, Title: "feature3", Merged: true, MergeCommitSHA: squashed},
+			4: {Number: 4, Title: "feature4", Merged: true, MergeCommitSHA: "0000000000000000000000000000000000000000"},
+		},
+	}
+
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Squashed:         true,
+		SquashDetection:  SquashDetectionSubject,
+	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
+
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
+	if err != nil {
+		t.Fatalf("fetch merged pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(fakeGitHub.searchQueries) != 1 {
+		t.Fatalf("got %d search queries, want 1: %v", len(fakeGitHub.searchQueries), fakeGitHub.searchQueries)
+	}
+	query := fakeGitHub.searchQueries[0]
+	if strings.Contains(query, shortSHA(squashed)) {
+		t.Fatalf("confirmed squash commit should not be searched: %q", query)
+	}
+	for _, sha := range []string{followUp, mismatched, unnumbered, issue} {
+		if !strings.Contains(query, shortSHA(sha)) {
+			t.Fatalf("expected %s in search query %q", shortSHA(sha), query)
+		}
+	}
+}
+
+func TestServiceFetchMergedPullRequestsFallsBackForUnresolvedCommits(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "feature1", Merged: true},
+		},
+	}
+
+	service := NewServiceWithClients(Config{
+		WorkDir:           workDir,
+		RemoteName:        DefaultRemoteName,
+		Repository:        Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:             "dummy",
+		ProductionBranch:  "master",
+		StagingBranch:     "staging",
+		PullRequestLookup: PullRequestLookupCommits,
+	}, NewGit(workDir), fakeGitHub, &bytes.Buffer{}, &bytes.Buffer{})
+
+	pullRequests, err := service.fetchMergedPullRequests(context.Background(), pathFilter{})
+	if err != nil {
+		t.Fatalf("fetch merged pull requests: %v", err)
+	}
+
+	if got, want := pullRequestNumbers(pullRequests), []int{1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got, want := fakeGitHub.getPullRequestCalls, [][]int{{1}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got fallback lookups %v, want %v", got, want)
+	}
+}
+
 func TestServiceRunDryRun(t *testing.T) {
 	t.Parallel()
 
@@ -211,10 +404,60 @@ func TestServiceRunUpdatesExistingReleasePullRequest(t *testing.T) {
 	}
 }
 
+func TestServiceRunReusesReleasePullRequestCreatedDespiteError(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {
+				Number: 1,
+				Title:  "Add feature",
+				Merged: true,
+				User:   User{LoginName: "alice"},
+			},
+		},
+		createErr: &APIError{Method: "POST", Path: "/repos/octo/example/pulls", StatusCode: 502},
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("service run: %v", err)
+	}
+
+	if fakeGitHub.createCalls != 1 {
+		t.Fatalf("got %d create calls, want 1", fakeGitHub.createCalls)
+	}
+	if !fakeGitHub.updateCalled {
+		t.Fatalf("expected the recovered pull request to be updated")
+	}
+	if !strings.Contains(stderr.String(), "Created pull request:") {
+		t.Fatalf("stderr does not contain create message: %q", stderr.String())
+	}
+}
+
 type fakeGitHubClient struct {
 	pullRequests        map[int]PullRequest
 	releasePullRequests []PullRequest
 	changedFiles        map[int][]ChangedFile
+	commitPullRequests  map[string][]PullRequest
+	createErr           error
+
+	mu                  sync.Mutex
+	getPullRequestCalls [][]int
+	commitLookups       []string
+	createCalls         int
 
 	updateCalled  bool
 	updatedTitle  string
@@ -226,6 +469,10 @@ type fakeGitHubClient struct {
 }
 
 func (f *fakeGitHubClient) GetPullRequests(_ context.Context, numbers []int) ([]PullRequest, error) {
+	f.mu.Lock()
+	f.getPullRequestCalls = append(f.getPullRequestCalls, append([]int(nil), numbers...))
+	f.mu.Unlock()
+
 	pullRequests := make([]PullRequest, 0, len(numbers))
 	for _, number := range numbers {
 		pr, ok := f.pullRequests[number]
@@ -242,7 +489,13 @@ func (f *fakeGitHubClient) ListOpenReleasePullRequests(_ context.Context, head,
 }
 
 func (f *fakeGitHubClient) CreatePullRequest(_ context.Context, title, head, base, body string) (*PullRequest, error) {
+	f.createCalls++
 	pr := PullRequest{Number: 100, Title: title, Body: body, URL: "https://example.com/pulls/100"}
+	if f.createErr != nil {
+		// Simulate a create that succeeded on the server but whose response was lost.
+		f.releasePullRequests = append(f.releasePullRequests, pr)
+		return nil, f.createErr
+	}
 	return &pr, nil
 }
 
@@ -250,7 +503,7 @@ func (f *fakeGitHubClient) UpdatePullRequest(_ context.Context, number int, titl
 	f.updateCalled = true
 	f.updatedTitle = title
 	f.updatedBody = body
-	pr := PullRequest{Number: number, Title: title, Body: body, URL: "https://example.com/pulls/99"}
+	pr := PullRequest{Number: number, Title: title, Body: body, URL: fmt.Sprintf("https://example.com/pulls/%d", number)}
 	return &pr, nil
 }
 
@@ -278,6 +531,13 @@ func (f *fakeGitHubClient) SearchPullRequestNumbers(_ context.Context, query str
 	return nil, nil
 }
 
+func (f *fakeGitHubClient) ListPullRequestsForCommit(_ context.Context, sha string) ([]PullRequest, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commitLookups = append(f.commitLookups, sha)
+	return f.commitPullRequests[sha], nil
+}
+
 func slicesContains(values []int, target int) bool {
 	for _, value := range values {
 		if value == target {
@@ -0,0 +1,165 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrPartialFailure is returned when some stages failed and the others did
+// not, which includes stages that had nothing to release.
+var ErrPartialFailure = errors.New("some release stages failed")
+
+const (
+	stageStatusCreated        = "created"
+	stageStatusUpdated        = "updated"
+	stageStatusDryRun         = "dry_run"
+	stageStatusNoPullRequests = "no_pull_requests"
+	stageStatusFailed         = "failed"
+)
+
+type Stage struct {
+	Name             string
+	StagingBranch    string
+	ProductionBranch string
+	TemplatePath     string
+	Title            string
+	Labels           []string
+	ExtraReviewers   []string
+	IncludePaths     []string
+	ExcludePaths     []string
+}
+
+type stageResult struct {
+	Name               string             `json:"name"`
+	StagingBranch      string             `json:"staging_branch"`
+	ProductionBranch   string             `json:"production_branch"`
+	Status             string             `json:"status"`
+	Error              string             `json:"error,omitempty"`
+	ReleasePullRequest *PullRequest       `json:"release_pull_request"`
+	MergedPullRequests []PullRequest      `json:"merged_pull_requests"`
+	ChangedFiles       []ChangedFile      `json:"changed_files"`
+	SuggestedVersion   *VersionSuggestion `json:"suggested_version,omitempty"`
+	Preview            *releasePreview    `json:"preview,omitempty"`
+}
+
+func (c Config) remoteBranch(branch string) string {
+	remoteName := c.RemoteName
+	if remoteName == "" {
+		remoteName = DefaultRemoteName
+	}
+	return remoteName + "/" + branch
+}
+
+func (c Config) stageName() string {
+	return c.StagingBranch + "->" + c.ProductionBranch
+}
+
+// forStage returns the configuration for a single stage. Settings the stage
+// leaves empty fall back to the top-level configuration.
+func (c Config) forStage(stage Stage) Config {
+	stageConfig := c
+	stageConfig.Stages = nil
+	if stage.StagingBranch != "" {
+		stageConfig.StagingBranch = stage.StagingBranch
+	}
+	if stage.ProductionBranch != "" {
+		stageConfig.ProductionBranch = stage.ProductionBranch
+	}
+	if stage.TemplatePath != "" {
+		stageConfig.TemplatePath = stage.TemplatePath
+	}
+	if stage.Title != "" {
+		stageConfig.Title = stage.Title
+	}
+	if stage.Labels != nil {
+		stageConfig.Labels = stage.Labels
+	}
+	if stage.ExtraReviewers != nil {
+		stageConfig.ExtraReviewers = stage.ExtraReviewers
+	}
+	if stage.IncludePaths != nil {
+		stageConfig.IncludePaths = stage.IncludePaths
+	}
+	if stage.ExcludePaths != nil {
+		stageConfig.ExcludePaths = stage.ExcludePaths
+	}
+	return stageConfig
+}
+
+func (s *Service) runStages(ctx context.Context) error {
+	results := make([]stageResult, 0, len(s.config.Stages))
+	var failures []error
+	released := 0
+
+	for idx, stage := range s.config.Stages {
+		stageConfig := s.config.forStage(stage)
+		if idx > 0 {
+			// The remote only needs to be updated once per run.
+			stageConfig.NoFetch = true
+		}
+		name := stage.Name
+		if name == "" {
+			name = stageConfig.stageName()
+		}
+
+		s.say(fmt.Sprintf("==> %s (%s -> %s)", name, stageConfig.StagingBranch, stageConfig.ProductionBranch))
+		stageService := &Service{
+			config: stageConfig,
+			git:    s.git,
+			github: s.github,
+			stdout: s.stdout,
+			stderr: s.stderr,
+		}
+		result, err := stageService.runStage(ctx)
+		result.Name = name
+
+		switch {
+		case err == nil:
+			released++
+		case errors.Is(err, ErrNoPullRequestsToRelease):
+		default:
+			result.Status = stageStatusFailed
+			result.Error = err.Error()
+			failures = append(failures, fmt.Errorf("stage %s: %w", name, err))
+			s.say(fmt.Sprintf("Stage %s failed: %v", name, err))
+		}
+		results = append(results, result)
+	}
+
+	s.summarizeStages(results)
+	s.reportActions(results, true)
+	if s.config.JSON {
+		encoder := json.NewEncoder(s.stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(struct {
+			Stages []stageResult `json:"stages"`
+		}{Stages: results})
+	}
+
+	switch {
+	case len(failures) == 0 && released == 0:
+		return ErrNoPullRequestsToRelease
+	case len(failures) == 0:
+		return nil
+	case len(failures) < len(results):
+		return fmt.Errorf("%w: %w", ErrPartialFailure, errors.Join(failures...))
+	default:
+		return errors.Join(failures...)
+	}
+}
+
+func (s *Service) summarizeStages(results []stageResult) {
+	s.say("Summary:")
+	for _, result := range results {
+		line := fmt.Sprintf("  %s: %s", result.Name, result.Status)
+		switch {
+		case result.Error != "":
+			line += " (" + result.Error + ")"
+		case result.ReleasePullRequest != nil && result.ReleasePullRequest.URL != "":
+			line += " " + result.ReleasePullRequest.URL
+		}
+		s.say(line)
+	}
+}
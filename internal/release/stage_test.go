@@ -0,0 +1,130 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServiceRunStagesReportsPartialFailure(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {
+				Number: 1,
+				Title:  "Add feature",
+				Merged: true,
+				User:   User{LoginName: "alice"},
+			},
+		},
+	}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Labels:           []string{"release"},
+		JSON:             true,
+		Stages: []Stage{
+			{Name: "production", StagingBranch: "staging", ProductionBranch: "master", Labels: []string{"qa"}},
+			{StagingBranch: "staging", ProductionBranch: "missing"},
+		},
+	}, NewGit(workDir), fakeGitHub, &stdout, &stderr)
+
+	err := service.Run(context.Background())
+	if !errors.Is(err, ErrPartialFailure) {
+		t.Fatalf("expected partial failure, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "stage staging->missing") {
+		t.Fatalf("error does not name the failing stage: %v", err)
+	}
+
+	if !reflect.DeepEqual(fakeGitHub.labels, []string{"qa"}) {
+		t.Fatalf("expected stage labels to override defaults, got %v", fakeGitHub.labels)
+	}
+
+	var payload struct {
+		Stages []stageResult `json:"stages"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &payload); err != nil {
+		t.Fatalf("unmarshal json output: %v\n%s", err, stdout.String())
+	}
+	if len(payload.Stages) != 2 {
+		t.Fatalf("got %d stages, want 2", len(payload.Stages))
+	}
+	if got := payload.Stages[0]; got.Name != "production" || got.Status != stageStatusCreated || len(got.MergedPullRequests) != 1 {
+		t.Fatalf("unexpected first stage: %+v", got)
+	}
+	if got := payload.Stages[0].SuggestedVersion; got == nil || got.Next != "v0.0.1" {
+		t.Fatalf("unexpected suggested version: %+v", got)
+	}
+	if got := payload.Stages[1]; got.Status != stageStatusFailed || got.Error == "" {
+		t.Fatalf("unexpected second stage: %+v", got)
+	}
+	if !strings.Contains(stderr.String(), "Summary:") {
+		t.Fatalf("stderr does not contain summary: %q", stderr.String())
+	}
+}
+
+func TestServiceRunStagesCountsStagesWithoutPullRequestsAsNotFailed(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+
+	var stdout, stderr bytes.Buffer
+	service := NewServiceWithClients(Config{
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Stages: []Stage{
+			{StagingBranch: "master", ProductionBranch: "master"},
+			{StagingBranch: "staging", ProductionBranch: "missing"},
+		},
+	}, NewGit(workDir), &fakeGitHubClient{}, &stdout, &stderr)
+
+	if err := service.Run(context.Background()); !errors.Is(err, ErrPartialFailure) {
+		t.Fatalf("expected partial failure, got %v", err)
+	}
+}
+
+func TestConfigForStageInheritsUnsetFields(t *testing.T) {
+	t.Parallel()
+
+	base := Config{
+		ProductionBranch: "main",
+		StagingBranch:    "staging",
+		TemplatePath:     "release.tmpl",
+		Labels:           []string{"release"},
+		ExtraReviewers:   []string{"bob"},
+		Stages:           []Stage{{StagingBranch: "develop"}},
+	}
+
+	got := base.forStage(Stage{StagingBranch: "develop", ProductionBranch: "staging", ExtraReviewers: []string{}})
+	if got.StagingBranch != "develop" || got.ProductionBranch != "staging" {
+		t.Fatalf("unexpected branches: %s -> %s", got.StagingBranch, got.ProductionBranch)
+	}
+	if got.TemplatePath != "release.tmpl" || !reflect.DeepEqual(got.Labels, []string{"release"}) {
+		t.Fatalf("expected unset fields to be inherited: %+v", got)
+	}
+	if len(got.ExtraReviewers) != 0 {
+		t.Fatalf("expected explicit empty reviewers to override: %v", got.ExtraReviewers)
+	}
+	if got.Stages != nil {
+		t.Fatalf("expected stage config not to carry stages")
+	}
+}
@@ -0,0 +1,79 @@
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type pendingPullRequest struct {
+	PullRequest
+	Listed bool `json:"listed"`
+}
+
+type statusResult struct {
+	ReleasePullRequest  *PullRequest         `json:"release_pull_request"`
+	Checklist           *checklistProgress   `json:"checklist,omitempty"`
+	PendingPullRequests []pendingPullRequest `json:"pending_pull_requests"`
+}
+
+// status shows the open release pull request, its checklist progress and the
+// pull requests waiting to be released.
+func (s *Service) status(ctx context.Context) error {
+	filter, err := s.config.pathFilter()
+	if err != nil {
+		return err
+	}
+	mergedPRs, err := s.fetchMergedPullRequests(ctx, filter)
+	if err != nil {
+		return err
+	}
+	releasePR, err := s.detectExistingReleasePullRequest(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := statusResult{ReleasePullRequest: releasePR, PendingPullRequests: make([]pendingPullRequest, 0, len(mergedPRs))}
+	listed := map[int]bool{}
+	if releasePR != nil {
+		progress, err := s.checklistProgress(ctx, releasePR.Body)
+		if err != nil {
+			return err
+		}
+		result.Checklist = &progress
+		for _, item := range progress.Items {
+			if item.Number > 0 {
+				listed[item.Number] = true
+			}
+		}
+	}
+	for _, pr := range mergedPRs {
+		result.PendingPullRequests = append(result.PendingPullRequests, pendingPullRequest{PullRequest: pr, Listed: listed[pr.Number]})
+	}
+
+	if s.config.JSON {
+		encoder := json.NewEncoder(s.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	if releasePR == nil {
+		fmt.Fprintf(s.stdout, "No open release pull request from %s to %s\n", s.config.StagingBranch, s.config.ProductionBranch)
+	} else {
+		fmt.Fprintf(s.stdout, "Release PR #%d %s\n  %s\n", releasePR.Number, releasePR.Title, releasePR.URL)
+		fmt.Fprintf(s.stdout, "Checklist: %d/%d checked\n", result.Checklist.Checked, result.Checklist.Checked+result.Checklist.Unchecked)
+		for _, line := range result.Checklist.uncheckedLines() {
+			fmt.Fprintln(s.stdout, line)
+		}
+	}
+
+	fmt.Fprintf(s.stdout, "Pending pull requests (%d):\n", len(result.PendingPullRequests))
+	for _, pr := range result.PendingPullRequests {
+		line := fmt.Sprintf("  #%d %s", pr.Number, pr.Title)
+		if releasePR != nil && !pr.Listed {
+			line += " (not in the release PR yet)"
+		}
+		fmt.Fprintln(s.stdout, line)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestServiceStatusReportsChecklistAndPendingPullRequests(t *testing.T) {
+	t.Parallel()
+
+	workDir := setupRepositoryWithMergedPullRequests(t)
+	fakeGitHub := &fakeGitHubClient{
+		pullRequests: map[int]PullRequest{
+			1: {Number: 1, Title: "Add feature", Merged: true, User: User{LoginName: "alice"}},
+			7: {Number: 7, Title: "Old change", Merged: true, User: User{LoginName: "carol"}},
+		},
+		releasePullRequests: []PullRequest{
+			{Number: 99, Title: "Release", Body: "- [x] #5 @bob\n- [ ] #7 @carol"},
+		},
+	}
+
+	var stdout bytes.Buffer
+	service := NewServiceWithClients(Config{
+		Command:          CommandStatus,
+		WorkDir:          workDir,
+		RemoteName:       DefaultRemoteName,
+		Repository:       Repository{Owner: "octo", Name: "example", Scheme: "https"},
+		Token:            "dummy",
+		ProductionBranch: "master",
+		StagingBranch:    "staging",
+		Mention:          "author",
+		NoFetch:          true,
+		JSON:             true,
+	}, NewGit(workDir), fakeGitHub, &stdout, io.Discard)
+
+	if err := service.Run(context.Background()); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+
+	var result statusResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("decode status: %v\n%s", err, stdout.String())
+	}
+	if result.ReleasePullRequest == nil || result.ReleasePullRequest.Number != 99 {
+		t.Fatalf("unexpected release pull request: %+v", result.ReleasePullRequest)
+	}
+	if result.Checklist == nil || result.Checklist.Checked != 1 || result.Checklist.Unchecked != 1 {
+		t.Fatalf("unexpected checklist: %+v", result.Checklist)
+	}
+	if got := result.Checklist.PendingUsers; len(got) != 1 || got[0] != "carol" {
+		t.Fatalf("unexpected pending users: %v", got)
+	}
+	if len(result.PendingPullRequests) != 1 || result.PendingPullRequests[0].Number != 1 || result.PendingPullRequests[0].Listed {
+		t.Fatalf("unexpected pending pull requests: %+v", result.PendingPullRequests)
+	}
+}
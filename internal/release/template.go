@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 	"text/template"
 
@@ -39,15 +39,29 @@ func (pr templatePullRequest) TargetUserLoginNames() []string {
 	return pr.PullRequest.TargetUserLoginNames(pr.mentionType)
 }
 
-func BuildTitleAndBody(
-	repoRoot string,
-	releasePR *PullRequest,
-	mergedPRs []PullRequest,
-	changedFiles []ChangedFile,
-	templatePath string,
-	mentionType string,
-) (string, string, error) {
-	templateText := DefaultTemplate
+func (pr templatePullRequest) Conventional() ConventionalCommit {
+	commit, _ := ParseConventionalCommit(pr.PullRequest)
+	return commit
+}
+
+// TemplateData is the input of a release template; makeTemplateData turns it
+// into the values templates see.
+type TemplateData struct {
+	ReleasePullRequest *PullRequest
+	MergedPullRequests []PullRequest
+	ChangedFiles       []ChangedFile
+	Mention            string
+	Categories         []Category
+	Version            VersionSuggestion
+	Tag                string
+}
+
+func BuildTitleAndBody(repoRoot, templatePath string, input TemplateData) (string, string, error) {
+	return renderTitleAndBody(repoRoot, templatePath, DefaultTemplate, input)
+}
+
+func renderTitleAndBody(repoRoot, templatePath, defaultTemplate string, input TemplateData) (string, string, error) {
+	templateText := defaultTemplate
 	if templatePath != "" {
 		fullPath := templatePath
 		if !filepath.IsAbs(templatePath) {
@@ -60,7 +74,7 @@ func BuildTitleAndBody(
 		templateText = string(body)
 	}
 
-	data := makeTemplateData(releasePR, mergedPRs, changedFiles, mentionType)
+	data := makeTemplateData(input)
 
 	tmpl, err := template.New("release").Funcs(sprig.FuncMap()).Parse(templateText)
 	if err != nil {
@@ -87,53 +101,158 @@ func BuildTitleAndBody(
 	return title, body, nil
 }
 
-func makeTemplateData(
-	releasePR *PullRequest,
-	mergedPRs []PullRequest,
-	changedFiles []ChangedFile,
-	mentionType string,
-) map[string]any {
+func makeTemplateData(input TemplateData) map[string]any {
+	mentionType := input.Mention
 	releaseView := templatePullRequest{mentionType: mentionType}
-	if releasePR != nil {
-		releaseView = templatePullRequest{PullRequest: *releasePR, mentionType: mentionType}
+	if input.ReleasePullRequest != nil {
+		releaseView = templatePullRequest{PullRequest: *input.ReleasePullRequest, mentionType: mentionType}
 	}
 
-	mergedViews := make([]templatePullRequest, 0, len(mergedPRs))
-	for _, pr := range mergedPRs {
-		mergedViews = append(mergedViews, templatePullRequest{PullRequest: pr, mentionType: mentionType})
+	mergedViews := make([]templatePullRequest, 0, len(input.MergedPullRequests))
+	byLabel := map[string][]templatePullRequest{}
+	byType := map[string][]templatePullRequest{}
+	for _, pr := range input.MergedPullRequests {
+		view := templatePullRequest{PullRequest: pr, mentionType: mentionType}
+		mergedViews = append(mergedViews, view)
+		for _, label := range uniqueStrings(pr.Labels) {
+			byLabel[label] = append(byLabel[label], view)
+		}
+		commitType := conventionalTypeOther
+		if commit, ok := ParseConventionalCommit(pr); ok {
+			commitType = commit.Type
+		}
+		byType[commitType] = append(byType[commitType], view)
 	}
+	categoryViews, uncategorized := groupByCategory(mergedViews, input.Categories)
+	changedFiles := input.ChangedFiles
 
 	return map[string]any{
-		"ReleasePullRequest":   releaseView,
-		"TargetPullRequest":    releaseView,
-		"MergedPullRequests":   mergedViews,
-		"PullRequests":         mergedViews,
-		"ChangedFiles":         changedFiles,
-		"release_pull_request": releaseView,
-		"target_pull_request":  releaseView,
-		"merged_pull_requests": mergedViews,
-		"pull_requests":        mergedViews,
-		"changed_files":        changedFiles,
+		"ReleasePullRequest":          releaseView,
+		"TargetPullRequest":           releaseView,
+		"MergedPullRequests":          mergedViews,
+		"PullRequests":                mergedViews,
+		"PullRequestsByLabel":         byLabel,
+		"Categories":                  categoryViews,
+		"UncategorizedPullRequests":   uncategorized,
+		"PullRequestsByType":          byType,
+		"CurrentVersion":              input.Version.Current,
+		"SuggestedVersion":            input.Version.Next,
+		"VersionBump":                 input.Version.Bump,
+		"Tag":                         input.Tag,
+		"ChangedFiles":                changedFiles,
+		"release_pull_request":        releaseView,
+		"target_pull_request":         releaseView,
+		"merged_pull_requests":        mergedViews,
+		"pull_requests":               mergedViews,
+		"pull_requests_by_label":      byLabel,
+		"categories":                  categoryViews,
+		"uncategorized_pull_requests": uncategorized,
+		"pull_requests_by_type":       byType,
+		"current_version":             input.Version.Current,
+		"suggested_version":           input.Version.Next,
+		"version_bump":                input.Version.Bump,
+		"tag":                         input.Tag,
+		"changed_files":               changedFiles,
 	}
 }
 
-var checklistLinePattern = regexp.MustCompile(`^- \[(?P<check>[ x])\] #(?P<number>\d+)\b`)
+// Category is a release note section collecting pull requests that carry
+// any of its labels.
+type Category struct {
+	Title  string
+	Labels []string
+}
 
-func MergeBodies(oldBody, newBody string) string {
-	checkStatus := map[string]string{}
-	for _, line := range splitLines(oldBody) {
-		matches := checklistLinePattern.FindStringSubmatch(line)
-		if len(matches) != 3 {
+type templateCategory struct {
+	Title        string
+	PullRequests []templatePullRequest
+}
+
+// groupByCategory puts each pull request into the first category whose
+// labels it matches. Categories without pull requests are omitted.
+func groupByCategory(pullRequests []templatePullRequest, categories []Category) ([]templateCategory, []templatePullRequest) {
+	grouped := make([][]templatePullRequest, len(categories))
+	var uncategorized []templatePullRequest
+	for _, pr := range pullRequests {
+		idx := slices.IndexFunc(categories, func(category Category) bool {
+			return hasAnyLabel(pr.PullRequest, category.Labels)
+		})
+		if idx < 0 {
+			uncategorized = append(uncategorized, pr)
 			continue
 		}
-		checkStatus[matches[2]] = matches[1]
+		grouped[idx] = append(grouped[idx], pr)
 	}
 
-	oldUnchecked := checklistLinePattern.ReplaceAllString(oldBody, "- [ ] #$2")
-	oldLines := splitLines(oldUnchecked)
+	views := make([]templateCategory, 0, len(categories))
+	for idx, category := range categories {
+		if len(grouped[idx]) == 0 {
+			continue
+		}
+		views = append(views, templateCategory{Title: category.Title, PullRequests: grouped[idx]})
+	}
+	return views, uncategorized
+}
+
+func hasAnyLabel(pr PullRequest, labels []string) bool {
+	for _, label := range pr.Labels {
+		for _, want := range labels {
+			if strings.EqualFold(label, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// excludeLabeled drops pull requests carrying any of the given labels.
+func excludeLabeled(pullRequests []PullRequest, labels []string) []PullRequest {
+	if len(labels) == 0 {
+		return pullRequests
+	}
+	kept := make([]PullRequest, 0, len(pullRequests))
+	for _, pr := range pullRequests {
+		if !hasAnyLabel(pr, labels) {
+			kept = append(kept, pr)
+		}
+	}
+	return kept
+}
+
+// MergeBodies keeps the state of every task item from oldBody, matched by
+// pull request or by its text under its parent item, and carries manual
+// regions over word for word.
+func MergeBodies(oldBody, newBody string, repository Repository) string {
+	newBody, newRegions := extractManualRegions(newBody)
+	oldBody, oldRegions := extractManualRegions(oldBody)
+	oldBody = dropManualPlaceholders(oldBody, func(key string) bool {
+		_, inNew := newRegions[key]
+		return !inNew
+	})
+
+	oldLines := splitLines(oldBody)
 	newLines := splitLines(newBody)
+	oldItems := parseTaskList(oldLines, repository)
+	newItems := parseTaskList(newLines, repository)
+	states := checklistStates(oldItems)
+
+	// A task item that is still listed has moved rather than been removed,
+	// so only its new position is kept.
+	listed := map[string]bool{}
+	for _, item := range newItems {
+		if item.task() {
+			listed[item.key] = true
+		}
+	}
+	moved := map[int]bool{}
+	for _, item := range oldItems {
+		if item.task() && listed[item.key] {
+			moved[item.line] = true
+		}
+	}
 
-	ops := diffLines(oldLines, newLines)
+	unchecked := func(taskListItem) string { return " " }
+	ops := diffLines(withMarks(oldLines, oldItems, unchecked), withMarks(newLines, newItems, unchecked))
 	mergedLines := make([]string, 0, len(oldLines)+len(newLines))
 	for i := 0; i < len(ops); {
 		if ops[i].kind == diffEqual {
@@ -147,7 +266,9 @@ func MergeBodies(oldBody, newBody string) string {
 		for i < len(ops) && ops[i].kind != diffEqual {
 			switch ops[i].kind {
 			case diffDelete:
-				deletes = append(deletes, ops[i].oldLine)
+				if !moved[ops[i].oldIndex] {
+					deletes = append(deletes, ops[i].oldLine)
+				}
 			case diffInsert:
 				inserts = append(inserts, ops[i].newLine)
 			}
@@ -160,30 +281,20 @@ func MergeBodies(oldBody, newBody string) string {
 		}
 
 		for idx := 0; idx < pairCount; idx++ {
-			oldLine := deletes[idx]
-			newLine := inserts[idx]
-			if isChecklistLine(oldLine) && isChecklistLine(newLine) {
-				mergedLines = append(mergedLines, oldLine)
-				continue
-			}
-			mergedLines = append(mergedLines, oldLine, newLine)
+			mergedLines = append(mergedLines, deletes[idx], inserts[idx])
 		}
 
 		mergedLines = append(mergedLines, deletes[pairCount:]...)
 		mergedLines = append(mergedLines, inserts[pairCount:]...)
 	}
 
-	mergedBody := strings.Join(mergedLines, "\n")
-	for number, status := range checkStatus {
-		pattern := regexp.MustCompile(`(?m)^- \[ \] #` + regexp.QuoteMeta(number) + `\b`)
-		mergedBody = pattern.ReplaceAllString(mergedBody, "- ["+status+"] #"+number)
-	}
-
-	return mergedBody
-}
-
-func isChecklistLine(line string) bool {
-	return checklistLinePattern.MatchString(line)
+	mergedLines = withMarks(mergedLines, parseTaskList(mergedLines, repository), func(item taskListItem) string {
+		if mark, ok := states[item.key]; ok {
+			return mark
+		}
+		return item.mark
+	})
+	return restoreManualRegions(strings.Join(mergedLines, "\n"), oldRegions, newRegions)
 }
 
 func splitLines(body string) []string {
@@ -207,9 +318,10 @@ const (
 )
 
 type diffOp struct {
-	kind    diffKind
-	oldLine string
-	newLine string
+	kind     diffKind
+	oldLine  string
+	newLine  string
+	oldIndex int
 }
 
 func diffLines(oldLines, newLines []string) []diffOp {
@@ -220,11 +332,11 @@ func diffLines(oldLines, newLines []string) []diffOp {
 	for i < len(oldLines) && j < len(newLines) {
 		switch {
 		case oldLines[i] == newLines[j]:
-			ops = append(ops, diffOp{kind: diffEqual, oldLine: oldLines[i], newLine: newLines[j]})
+			ops = append(ops, diffOp{kind: diffEqual, oldLine: oldLines[i], newLine: newLines[j], oldIndex: i})
 			i++
 			j++
 		case matrix[i+1][j] >= matrix[i][j+1]:
-			ops = append(ops, diffOp{kind: diffDelete, oldLine: oldLines[i]})
+			ops = append(ops, diffOp{kind: diffDelete, oldLine: oldLines[i], oldIndex: i})
 			i++
 		default:
 			ops = append(ops, diffOp{kind: diffInsert, newLine: newLines[j]})
@@ -232,7 +344,7 @@ func diffLines(oldLines, newLines []string) []diffOp {
 		}
 	}
 	for ; i < len(oldLines); i++ {
-		ops = append(ops, diffOp{kind: diffDelete, oldLine: oldLines[i]})
+		ops = append(ops, diffOp{kind: diffDelete, oldLine: oldLines[i], oldIndex: i})
 	}
 	for ; j < len(newLines); j++ {
 		ops = append(ops, diffOp{kind: diffInsert, newLine: newLines[j]})
@@ -3,6 +3,7 @@ package release
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -10,14 +11,9 @@ import (
 func TestBuildTitleAndBodyDefaultTemplate(t *testing.T) {
 	t.Parallel()
 
-	title, body, err := BuildTitleAndBody(
-		t.TempDir(),
-		nil,
-		[]PullRequest{{Number: 3, User: User{LoginName: "hakobe"}}},
-		nil,
-		"",
-		"",
-	)
+	title, body, err := BuildTitleAndBody(t.TempDir(), "", TemplateData{
+		MergedPullRequests: []PullRequest{{Number: 3, User: User{LoginName: "hakobe"}}},
+	})
 	if err != nil {
 		t.Fatalf("build title and body: %v", err)
 	}
@@ -43,14 +39,9 @@ func TestBuildTitleAndBodyCustomTemplate(t *testing.T) {
 		t.Fatalf("write template: %v", err)
 	}
 
-	title, body, err := BuildTitleAndBody(
-		root,
-		nil,
-		[]PullRequest{{Number: 4, Title: "Add feature", User: User{LoginName: "alice"}}},
-		nil,
-		"template.tmpl",
-		"",
-	)
+	title, body, err := BuildTitleAndBody(root, "template.tmpl", TemplateData{
+		MergedPullRequests: []PullRequest{{Number: 4, Title: "Add feature", User: User{LoginName: "alice"}}},
+	})
 	if err != nil {
 		t.Fatalf("build title and body: %v", err)
 	}
@@ -63,6 +54,71 @@ func TestBuildTitleAndBodyCustomTemplate(t *testing.T) {
 	}
 }
 
+func TestBuildTitleAndBodyGroupsPullRequestsByCategory(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	templatePath := filepath.Join(root, "template.tmpl")
+	if err := os.WriteFile(templatePath, []byte(`Release
+{{- range .Categories }}
+## {{ .Title }}
+{{- range .PullRequests }}
+{{ .ToChecklistItemWithTitle }}
+{{- end }}
+{{- end }}
+## Others
+{{- range .UncategorizedPullRequests }}
+{{ .ToChecklistItemWithTitle }}
+{{- end }}
+## Labeled bug: {{ len (index .PullRequestsByLabel "bug") }}
+`), 0o600); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+
+	_, body, err := BuildTitleAndBody(root, "template.tmpl", TemplateData{
+		MergedPullRequests: []PullRequest{
+			{Number: 1, Title: "Add search", Labels: []string{"feature"}},
+			{Number: 2, Title: "Fix crash", Labels: []string{"Bug", "feature"}},
+			{Number: 3, Title: "Bump deps", Labels: []string{"dependencies"}},
+			{Number: 4, Title: "Fix typo", Labels: []string{"bug"}},
+		},
+		Categories: []Category{
+			{Title: "Bug fixes", Labels: []string{"bug"}},
+			{Title: "Features", Labels: []string{"feature", "enhancement"}},
+			{Title: "Documentation", Labels: []string{"docs"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("build title and body: %v", err)
+	}
+
+	want := `## Bug fixes
+- [ ] #2 Fix crash
+- [ ] #4 Fix typo
+## Features
+- [ ] #1 Add search
+## Others
+- [ ] #3 Bump deps
+## Labeled bug: 1`
+	if got := strings.TrimSpace(body); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestExcludeLabeled(t *testing.T) {
+	t.Parallel()
+
+	pullRequests := []PullRequest{
+		{Number: 1, Labels: []string{"feature"}},
+		{Number: 2, Labels: []string{"Skip-Release-Notes"}},
+		{Number: 3},
+	}
+	got := pullRequestNumbers(excludeLabeled(pullRequests, []string{"skip-release-notes"}))
+	if want := []int{1, 3}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
 func TestMergeBodiesPreservesChecklistState(t *testing.T) {
 	t.Parallel()
 
@@ -112,7 +168,7 @@ func TestMergeBodiesPreservesChecklistState(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			if got := MergeBodies(tt.oldBody, tt.newBody); got != tt.want {
+			if got := MergeBodies(tt.oldBody, tt.newBody, Repository{Owner: "octo", Name: "example"}); got != tt.want {
 				t.Fatalf("got:\n%s\n\nwant:\n%s", got, tt.want)
 			}
 		})
@@ -6,11 +6,25 @@ import (
 	"time"
 )
 
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+)
+
 type Repository struct {
-	Host   string
-	Scheme string
-	Owner  string
-	Name   string
+	Host     string
+	Scheme   string
+	Owner    string
+	Name     string
+	Provider string
+}
+
+func (r Repository) ProviderName() string {
+	if r.Provider == "" {
+		return ProviderGitHub
+	}
+	return r.Provider
 }
 
 func (r Repository) FullName() string {
@@ -28,11 +42,29 @@ func (r Repository) APIBaseURL() string {
 	if scheme == "" {
 		scheme = "https"
 	}
-	return fmt.Sprintf("%s://%s/api/v3/", scheme, r.Host)
+	switch r.ProviderName() {
+	case ProviderGitLab:
+		return fmt.Sprintf("%s://%s/api/v4/", scheme, r.Host)
+	case ProviderGitea:
+		return fmt.Sprintf("%s://%s/api/v1/", scheme, r.Host)
+	default:
+		return fmt.Sprintf("%s://%s/api/v3/", scheme, r.Host)
+	}
+}
+
+func (r Repository) GraphQLURL() string {
+	if r.Host == "" {
+		return "https://api.github.com/graphql"
+	}
+	scheme := r.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/api/graphql", scheme, r.Host)
 }
 
 func (r Repository) HeadRef(branch string) string {
-	if r.Owner == "" {
+	if r.Owner == "" || r.ProviderName() != ProviderGitHub {
 		return branch
 	}
 	return r.Owner + ":" + branch
@@ -58,6 +90,8 @@ type PullRequest struct {
 	User           User      `json:"user,omitempty"`
 	Assignee       *User     `json:"assignee,omitempty"`
 	Assignees      []User    `json:"assignees,omitempty"`
+	Labels         []string  `json:"labels,omitempty"`
+	Milestone      string    `json:"milestone,omitempty"`
 }
 
 func (pr PullRequest) HTMLLink() string {